@@ -1,16 +1,41 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"google.golang.org/genproto/googleapis/type/latlng"
 	"log/slog"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 	"wayfarer/internal/config"
+	"wayfarer/internal/discord"
+	"wayfarer/internal/email"
+	"wayfarer/internal/evaluation"
 	"wayfarer/internal/googlemaps"
+	"wayfarer/internal/gotify"
+	"wayfarer/internal/history"
+	"wayfarer/internal/notify"
+	"wayfarer/internal/ntfy"
+	"wayfarer/internal/opentripplanner"
+	"wayfarer/internal/osrm"
+	"wayfarer/internal/pushover"
+	"wayfarer/internal/routing"
 	"wayfarer/internal/scheduling"
+	"wayfarer/internal/slack"
 	"wayfarer/internal/telegram"
+	"wayfarer/internal/valhalla"
+	"wayfarer/internal/webhook"
+)
+
+const (
+	// How long in-flight evaluations may take to finish on shutdown before they are cancelled
+	shutdownTimeout = 30 * time.Second
+	// How often the config file is checked for changes
+	configPollInterval = 5 * time.Second
 )
 
 func main() {
@@ -23,56 +48,238 @@ func main() {
 	configFilePath := flag.String("config-file", "config.yaml", "Path of config file")
 	flag.Parse()
 
-	// Load environment variables
+	// Load configuration
+	cfg, err := config.LoadConfig(*configFilePath)
+	if err != nil {
+		slog.Error("Failed to load config", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Open the history database, kept open across config reloads
+	var measurements evaluation.MeasurementStore
+	var historyStore *history.Store
+	if cfg.History.Path != "" {
+		historyStore, err = history.Open(cfg.History.Path)
+		if err != nil {
+			slog.Error("Failed to open history", slog.Any("error", err))
+			os.Exit(1)
+		}
+		measurements = historyStore
+	}
+
+	// Evaluations use their own context, so those in flight can finish after a shutdown signal
+	shutdownCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+
+	// Start scheduling tasks
+	runner := newRunner(runCtx, measurements)
+	if err := runner.apply(cfg); err != nil {
+		slog.Error("Failed to schedule rules", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Reload the config when the file changes or on SIGHUP
+	go config.WatchFile(shutdownCtx, *configFilePath, configPollInterval, func() {
+		slog.Info("Config file changed, reloading")
+		runner.reload(*configFilePath)
+	})
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	// Run until SIGINT or SIGTERM, e.g. from docker stop
+Run:
+	for {
+		select {
+		case <-hangup:
+			slog.Info("Received SIGHUP, reloading config")
+			runner.reload(*configFilePath)
+		case <-shutdownCtx.Done():
+			break Run
+		}
+	}
+	stop()
+	slog.Info("Shutting down")
+
+	handles := runner.stop()
+	if !waitForEvaluations(handles, shutdownTimeout) {
+		slog.Warn("Timed out waiting for evaluations to finish, cancelling them")
+		cancelRun()
+		waitForEvaluations(handles, 5*time.Second)
+	}
+	runner.close()
+	if historyStore != nil {
+		if err := historyStore.Close(); err != nil {
+			slog.Error("Failed to close history", slog.Any("error", err))
+		}
+	}
+	slog.Info("Shutdown complete")
+}
+
+// waitForEvaluations waits for evaluations in flight, returning false on timeout
+func waitForEvaluations(handles []*scheduling.Handle, timeout time.Duration) bool {
+	var wg sync.WaitGroup
+	for _, handle := range handles {
+		wg.Go(handle.Wait)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// newTelegramClient reads the bot token from the environment
+func newTelegramClient() (*telegram.Client, error) {
 	telegramBotToken := os.Getenv("TELEGRAM_BOT_TOKEN")
 	if telegramBotToken == "" {
-		slog.Error("TELEGRAM_BOT_TOKEN environment variable must be set")
-		os.Exit(1)
+		return nil, errors.New("TELEGRAM_BOT_TOKEN environment variable must be set")
 	}
 	telegramApiBaseUrl := os.Getenv("TELEGRAM_API_BASE_URL")
 	if telegramApiBaseUrl == "" {
 		telegramApiBaseUrl = "https://api.telegram.org"
 	}
-	googleApiKey := os.Getenv("GOOGLE_API_KEY")
-	if googleApiKey == "" {
-		slog.Error("GOOGLE_API_KEY environment variable must be set")
-		os.Exit(1)
+	return telegram.NewClient(telegramApiBaseUrl, telegramBotToken), nil
+}
+
+// newRoutingProviders initializes only the providers used by rules, so unused providers need no credentials
+func newRoutingProviders(cfg *config.Config) (map[string]routing.Provider, error) {
+	providers := make(map[string]routing.Provider)
+	if cfg.UsesProvider(config.ProviderGoogle) {
+		googleApiKey := os.Getenv("GOOGLE_API_KEY")
+		if googleApiKey == "" {
+			return nil, errors.New("GOOGLE_API_KEY environment variable must be set")
+		}
+		googleApiBaseUrl := os.Getenv("GOOGLE_API_BASE_URL")
+		mapsRoutingService, err := googlemaps.NewMapsRoutingService(googleApiBaseUrl, googleApiKey, newRetrySettings(cfg.Routing.Google))
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Google Maps client: %w", err)
+		}
+		if cfg.Routing.Google.Batch {
+			providers[config.ProviderGoogle] = googlemaps.NewBatchingRoutingService(mapsRoutingService)
+		} else {
+			providers[config.ProviderGoogle] = mapsRoutingService
+		}
 	}
-	googleApiBaseUrl := os.Getenv("GOOGLE_API_BASE_URL")
+	if cfg.UsesProvider(config.ProviderOpenTripPlanner) {
+		providers[config.ProviderOpenTripPlanner] = opentripplanner.NewClient(cfg.Routing.OpenTripPlanner.Url)
+	}
+	if cfg.UsesProvider(config.ProviderOsrm) {
+		providers[config.ProviderOsrm] = osrm.NewClient(cfg.Routing.Osrm.Url)
+	}
+	if cfg.UsesProvider(config.ProviderValhalla) {
+		providers[config.ProviderValhalla] = valhalla.NewClient(cfg.Routing.Valhalla.Url)
+	}
+	if cfg.Routing.CacheTTL != "" {
+		ttl, _ := time.ParseDuration(cfg.Routing.CacheTTL)
+		for name, provider := range providers {
+			providers[name] = routing.NewCachingProvider(provider, ttl)
+		}
+	}
+	return providers, nil
+}
 
-	// Load configuration
-	cfg, err := config.LoadConfig(*configFilePath)
-	if err != nil {
-		slog.Error("Failed to load config", slog.Any("error", err))
-		os.Exit(1)
+// newRetrySettings applies the defaults to the settings which aren't configured
+func newRetrySettings(google config.Google) googlemaps.RetrySettings {
+	settings := googlemaps.DefaultRetrySettings
+	if google.Timeout != "" {
+		settings.Timeout, _ = time.ParseDuration(google.Timeout)
 	}
+	if google.MaxAttempts > 0 {
+		settings.MaxAttempts = google.MaxAttempts
+	}
+	return settings
+}
 
-	// Initialize clients
-	telegramClient := telegram.NewClient(telegramApiBaseUrl, telegramBotToken)
-	mapsRoutingService, err := googlemaps.NewMapsRoutingService(googleApiBaseUrl, googleApiKey)
+// ruleNotifiers notify the user of a rule, or the channels of its levels
+type ruleNotifiers struct {
+	user   notify.Notifier
+	levels []notify.Notifier // Indexed like the levels of the rule, nil if the level has no channels
+}
+
+func newRuleNotifiers(telegramClient *telegram.Client, emailClient *email.Client, rule config.Rule) (ruleNotifiers, error) {
+	user, err := newNotifier(telegramClient, emailClient, rule.User.NotificationChannels())
 	if err != nil {
-		slog.Error("Failed to initialize Google Maps client", slog.Any("error", err))
-		os.Exit(1)
+		return ruleNotifiers{}, err
 	}
-
-	// Start scheduling tasks
-	for _, rule := range cfg.Rules {
-		err := scheduleRuleEvaluations(telegramClient, mapsRoutingService, rule)
+	notifiers := ruleNotifiers{user: user, levels: make([]notify.Notifier, len(rule.TravelTime.Levels))}
+	for i, level := range rule.TravelTime.Levels {
+		if len(level.Channels) == 0 {
+			continue
+		}
+		notifiers.levels[i], err = newNotifier(telegramClient, emailClient, level.Channels)
 		if err != nil {
-			slog.Error("Failed to schedule rule", slog.Any("error", err), slog.Any("rule_id", rule.Id))
-			os.Exit(1)
+			return ruleNotifiers{}, err
 		}
 	}
+	return notifiers, nil
+}
 
-	// Keep the main thread alive
-	select {}
+// newNotifier creates a notifier sending to every channel
+func newNotifier(telegramClient *telegram.Client, emailClient *email.Client, channels []config.Channel) (notify.Notifier, error) {
+	var notifiers notify.Notifiers
+	for _, channel := range channels {
+		switch channel.Type {
+		case config.ChannelTelegram:
+			notifiers = append(notifiers, &telegram.ChatNotifier{Client: telegramClient, ChatID: channel.TelegramUserID})
+		case config.ChannelSlack:
+			notifiers = append(notifiers, slack.NewClient(channel.WebhookUrl))
+		case config.ChannelDiscord:
+			notifiers = append(notifiers, discord.NewClient(channel.WebhookUrl))
+		case config.ChannelWebhook:
+			client, err := webhook.NewClient(channel.WebhookUrl, channel.BodyTemplate)
+			if err != nil {
+				return nil, err
+			}
+			notifiers = append(notifiers, client)
+		case config.ChannelEmail:
+			notifiers = append(notifiers, &email.Notifier{Client: emailClient, From: channel.From, To: channel.To})
+		case config.ChannelNtfy:
+			notifiers = append(notifiers, ntfy.NewClient(channel.Url, channel.Token))
+		case config.ChannelGotify:
+			notifiers = append(notifiers, gotify.NewClient(channel.Url, channel.Token))
+		case config.ChannelPushover:
+			notifiers = append(notifiers, pushover.NewClient(pushover.DefaultApiBaseUrl, channel.Token, channel.UserKey))
+		}
+	}
+	return notifiers, nil
+}
+
+// newEmailClient reads SMTP credentials from the environment, which are optional for unauthenticated relays
+func newEmailClient(smtpConfig config.Smtp) *email.Client {
+	port := smtpConfig.Port
+	if port == 0 {
+		port = 587
+	}
+	security := smtpConfig.Security
+	if security == "" {
+		security = email.SecurityStartTLS
+	}
+	return email.NewClient(smtpConfig.Host, port, security, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
 }
 
-func scheduleRuleEvaluations(telegramClient *telegram.Client, mapsRoutingService *googlemaps.MapsRoutingService, rule config.Rule) error {
+func scheduleRuleEvaluations(ctx context.Context, evaluator *evaluation.Evaluator, rule config.Rule) (*scheduling.Handle, error) {
 	// Convert config as needed
 	// Already validated in config.validate()
 	schedules := make([]scheduling.Schedule, 0, len(rule.Times))
 	for _, t := range rule.Times {
+		if t.Cron != "" {
+			cron, _ := scheduling.ParseCron(t.Cron)
+			schedules = append(schedules, scheduling.Schedule{Cron: cron})
+			continue
+		}
+		if t.IsWindow() {
+			schedules = append(schedules, toWindow(t).Schedules()...)
+			continue
+		}
 		weekday, _ := config.ParseWeekday(t.Day)
 		timeOfDay, _ := time.Parse("15:04", t.Time)
 		schedule := scheduling.Schedule{
@@ -83,23 +290,25 @@ func scheduleRuleEvaluations(telegramClient *telegram.Client, mapsRoutingService
 		schedules = append(schedules, schedule)
 	}
 	timezone, _ := time.LoadLocation(rule.Timezone)
-	origin := &latlng.LatLng{Latitude: rule.Origin.Latitude, Longitude: rule.Origin.Longitude}
-	destination := &latlng.LatLng{Latitude: rule.Destination.Latitude, Longitude: rule.Destination.Longitude}
 
-	return scheduling.ScheduleFunction(schedules, timezone, rule.Holidays, func() {
-		routeDuration, err := mapsRoutingService.FetchCurrentTransitTimeBetween(origin, destination)
-		if err != nil {
-			slog.Error("Failed to fetch transit time", slog.Any("error", err), slog.Any("rule_id", rule.Id))
-			return
-		}
-		if routeDuration.Minutes() > float64(rule.TravelTime.NotificationThresholdMinutes) {
-			slog.Info("Travel time exceeds threshold", slog.Any("rule_id", rule.Id), slog.Any("duration", routeDuration))
-			message := fmt.Sprintf("Travel time between %s and %s is greater than %d minutes: currently scheduled to take %.0f minutes",
-				rule.Origin.Name, rule.Destination.Name, rule.TravelTime.NotificationThresholdMinutes, routeDuration.Minutes())
-			err := telegramClient.SendMessage(rule.User.TelegramUserID, message)
-			if err != nil {
-				slog.Error("Failed to send message", slog.Any("error", err), slog.Any("rule_id", rule.Id))
-			}
-		}
-	})
+	return scheduling.ScheduleFunction(ctx, schedules, timezone, rule.Holidays, evaluator.Evaluate)
+}
+
+func toWindow(t config.TimeSchedule) scheduling.Window {
+	days := make([]time.Weekday, 0, len(t.Days))
+	for _, day := range t.Days {
+		weekday, _ := config.ParseWeekday(day)
+		days = append(days, weekday)
+	}
+	from, _ := time.Parse("15:04", t.From)
+	to, _ := time.Parse("15:04", t.To)
+	every, _ := time.ParseDuration(t.Every)
+	return scheduling.Window{
+		Days:       days,
+		FromHour:   from.Hour(),
+		FromMinute: from.Minute(),
+		ToHour:     to.Hour(),
+		ToMinute:   to.Minute(),
+		Every:      every,
+	}
 }
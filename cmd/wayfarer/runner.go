@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"wayfarer/internal/config"
+	"wayfarer/internal/email"
+	"wayfarer/internal/evaluation"
+	"wayfarer/internal/googlemaps"
+	"wayfarer/internal/routing"
+	"wayfarer/internal/scheduling"
+	"wayfarer/internal/telegram"
+)
+
+// runner keeps the scheduled evaluations in sync with the config as it is reloaded
+type runner struct {
+	ctx          context.Context // Passed to evaluations
+	measurements evaluation.MeasurementStore
+
+	mu             sync.Mutex
+	cfg            *config.Config
+	telegramClient *telegram.Client
+	geocoder       *googlemaps.Geocoder // Kept across reloads, so locations are only geocoded once
+	providers      map[string]routing.Provider
+	handles        map[int]*scheduling.Handle    // By rule ID
+	evaluators     map[int]*evaluation.Evaluator // By rule ID, so the status of rescheduled rules can be kept
+}
+
+func newRunner(ctx context.Context, measurements evaluation.MeasurementStore) *runner {
+	return &runner{
+		ctx:          ctx,
+		measurements: measurements,
+		handles:      make(map[int]*scheduling.Handle),
+		evaluators:   make(map[int]*evaluation.Evaluator),
+	}
+}
+
+// reload loads the config file again, keeping the current config if it is invalid
+func (r *runner) reload(configFilePath string) {
+	cfg, err := config.LoadConfig(configFilePath)
+	if err != nil {
+		slog.Error("Failed to reload config, keeping the previous one", slog.Any("error", err))
+		return
+	}
+	if err := r.apply(cfg); err != nil {
+		slog.Error("Failed to apply reloaded config, keeping the previous one", slog.Any("error", err))
+	}
+}
+
+// apply schedules the rules of the config in place of the current ones: removed rules are cancelled, new rules are
+// scheduled and changed rules are rescheduled. Unchanged rules keep running. If the clients for the config can't be
+// created, nothing changes.
+func (r *runner) apply(cfg *config.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cfg.Routing.Google.Geocode {
+		if err := r.geocode(cfg); err != nil {
+			return err
+		}
+	}
+	changes := config.DiffRules(r.cfg, cfg)
+	if r.cfg != nil && r.cfg.History != cfg.History {
+		slog.Warn("Changes to the history settings only take effect after a restart")
+	}
+
+	// Create everything needed before touching the running schedules
+	telegramClient := r.telegramClient
+	if telegramClient == nil && cfg.UsesChannel(config.ChannelTelegram) {
+		var err error
+		telegramClient, err = newTelegramClient()
+		if err != nil {
+			return err
+		}
+	}
+	var emailClient *email.Client
+	if cfg.UsesChannel(config.ChannelEmail) {
+		emailClient = newEmailClient(cfg.Smtp)
+	}
+	providers := r.providers
+	replaceProviders := r.needsNewProviders(cfg)
+	if replaceProviders {
+		var err error
+		providers, err = newRoutingProviders(cfg)
+		if err != nil {
+			return err
+		}
+		// Evaluations of unchanged rules still use the previous providers, so reschedule them too
+		changes.Changed = changesForAllRules(cfg, changes)
+	}
+	scheduled := slices.Concat(changes.Added, changes.Changed)
+	notifiers := make(map[int]ruleNotifiers, len(scheduled))
+	for _, rule := range scheduled {
+		notifier, err := newRuleNotifiers(telegramClient, emailClient, rule)
+		if err != nil {
+			if replaceProviders {
+				closeProviders(providers)
+			}
+			return err
+		}
+		notifiers[rule.Id] = notifier
+	}
+
+	// Swap the schedules
+	var retired []*scheduling.Handle
+	for _, rule := range slices.Concat(changes.Removed, changes.Changed) {
+		if handle, ok := r.handles[rule.Id]; ok {
+			handle.Cancel()
+			retired = append(retired, handle)
+			delete(r.handles, rule.Id)
+		}
+	}
+	for _, rule := range changes.Removed {
+		delete(r.evaluators, rule.Id)
+	}
+	var errs []error
+	for _, rule := range scheduled {
+		rule.Provider = cfg.ProviderFor(rule) // Resolve the default so it is recorded in the history
+		evaluator := evaluation.NewEvaluator(rule, providers[rule.Provider], notifiers[rule.Id].user, notifiers[rule.Id].levels, r.measurements)
+		if previous, ok := r.evaluators[rule.Id]; ok && r.sameThresholds(rule) {
+			// Keep an ongoing delay, so it isn't notified again and its recovery still is
+			evaluator.Restore(previous.State())
+		}
+		handle, err := scheduleRuleEvaluations(r.ctx, evaluator, rule)
+		if err != nil {
+			slog.Error("Failed to schedule rule", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+			errs = append(errs, err)
+			delete(r.evaluators, rule.Id)
+			continue
+		}
+		r.handles[rule.Id] = handle
+		r.evaluators[rule.Id] = evaluator
+	}
+
+	// Close replaced providers once the evaluations using them have finished
+	if replaceProviders && r.providers != nil {
+		previous := r.providers
+		go func() {
+			for _, handle := range retired {
+				handle.Wait()
+			}
+			closeProviders(previous)
+		}()
+	}
+
+	if r.cfg != nil {
+		slog.Info("Reloaded config", slog.Int("added_rules", len(changes.Added)),
+			slog.Int("removed_rules", len(changes.Removed)), slog.Int("changed_rules", len(changes.Changed)))
+	}
+	r.cfg = cfg
+	if r.telegramClient != nil {
+		// Try chats which were unreachable again, e.g. the user may have unblocked the bot
+		r.telegramClient.ResetUnreachable()
+	}
+	r.telegramClient = telegramClient
+	r.providers = providers
+	return errors.Join(errs...)
+}
+
+// geocode resolves the addresses and place IDs of the config to coordinates, logging them so they can be checked
+func (r *runner) geocode(cfg *config.Config) error {
+	if r.geocoder == nil {
+		googleApiKey := os.Getenv("GOOGLE_API_KEY")
+		if googleApiKey == "" {
+			return errors.New("GOOGLE_API_KEY environment variable must be set to geocode locations")
+		}
+		r.geocoder = googlemaps.NewGeocoder(googleApiKey)
+	}
+	for i := range cfg.Rules {
+		for _, location := range cfg.Rules[i].Locations() {
+			if location.HasCoordinates() {
+				continue
+			}
+			coordinates, err := r.geocoder.Geocode(r.ctx, location.Address, location.PlaceID)
+			if err != nil {
+				return fmt.Errorf("failed to geocode %s: %w", location.Name, err)
+			}
+			location.Latitude, location.Longitude = coordinates.Latitude, coordinates.Longitude
+			slog.Info("Geocoded location", slog.Any("rule_id", cfg.Rules[i].Id), slog.String("name", location.Name),
+				slog.Float64("latitude", location.Latitude), slog.Float64("longitude", location.Longitude))
+		}
+	}
+	return nil
+}
+
+// needsNewProviders checks if the routing settings changed or a rule uses a provider which isn't initialized
+func (r *runner) needsNewProviders(cfg *config.Config) bool {
+	if r.cfg == nil || r.cfg.Routing != cfg.Routing {
+		return true
+	}
+	for _, rule := range cfg.Rules {
+		if _, ok := r.providers[cfg.ProviderFor(rule)]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sameThresholds checks if the rule had the same travel time thresholds in the current config, so its status still
+// applies
+func (r *runner) sameThresholds(rule config.Rule) bool {
+	if r.cfg == nil {
+		return false
+	}
+	for _, current := range r.cfg.Rules {
+		if current.Id == rule.Id {
+			return reflect.DeepEqual(current.TravelTime, rule.TravelTime)
+		}
+	}
+	return false
+}
+
+// changesForAllRules returns every rule of the config which isn't new
+func changesForAllRules(cfg *config.Config, changes config.RuleChanges) []config.Rule {
+	added := make(map[int]bool)
+	for _, rule := range changes.Added {
+		added[rule.Id] = true
+	}
+	var changed []config.Rule
+	for _, rule := range cfg.Rules {
+		if !added[rule.Id] {
+			changed = append(changed, rule)
+		}
+	}
+	return changed
+}
+
+// stop cancels all schedules, returning their handles so evaluations in flight can be waited for
+func (r *runner) stop() []*scheduling.Handle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	handles := make([]*scheduling.Handle, 0, len(r.handles))
+	for _, handle := range r.handles {
+		handle.Cancel()
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+func (r *runner) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	closeProviders(r.providers)
+}
+
+func closeProviders(providers map[string]routing.Provider) {
+	for name, provider := range providers {
+		if err := provider.Close(); err != nil {
+			slog.Error("Failed to close routing provider", slog.Any("error", err), slog.String("provider", name))
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"wayfarer/internal/config"
+)
+
+func testConfig(osrmUrl string, webhookUrl string) *config.Config {
+	return &config.Config{
+		Routing: config.Routing{Provider: config.ProviderOsrm, Osrm: config.Osrm{Url: osrmUrl}},
+		Rules: []config.Rule{{
+			Id:          1,
+			Origin:      config.Location{Name: "Home", Latitude: 51.503, Longitude: -0.1276},
+			Destination: config.Location{Name: "Office", Latitude: 51.513, Longitude: -0.0877},
+			User:        config.User{Channels: []config.Channel{{Type: config.ChannelWebhook, WebhookUrl: webhookUrl}}},
+			TravelTime:  config.TravelTime{NotificationThresholdMinutes: 30},
+			Times:       []config.TimeSchedule{{Day: "Monday", Time: "03:00"}},
+			Timezone:    "UTC",
+			TravelMode:  "DRIVE",
+		}},
+	}
+}
+
+func TestRunner_ReloadKeepsDelay(t *testing.T) {
+	// given
+	var durationSeconds atomic.Int64
+	durationSeconds.Store(40 * 60)
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"code":"Ok","routes":[{"duration":%d}]}`, durationSeconds.Load())
+	}))
+	defer osrmServer.Close()
+	var mu sync.Mutex
+	var messages []string
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, string(body))
+	}))
+	defer webhookServer.Close()
+
+	r := newRunner(context.Background(), nil)
+	defer r.close()
+	defer r.stop()
+	if err := r.apply(testConfig(osrmServer.URL, webhookServer.URL)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.evaluators[1].Evaluate(context.Background())
+
+	// when
+	reloaded := testConfig(osrmServer.URL, webhookServer.URL)
+	reloaded.Routing.Google.Timeout = "5s" // Unrelated to the rule, but reschedules it with new providers
+	if err := r.apply(reloaded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.evaluators[1].Evaluate(context.Background())
+	durationSeconds.Store(20 * 60)
+	r.evaluators[1].Evaluate(context.Background())
+
+	// then
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 2 || !strings.Contains(messages[0], "greater than 30 minutes") || !strings.Contains(messages[1], "back to normal") {
+		t.Errorf("expected a single delay and its recovery, got %q", messages)
+	}
+}
+
+func TestRunner_ReloadWithNewThresholdsResetsStatus(t *testing.T) {
+	// given
+	osrmServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"duration":2400}]}`))
+	}))
+	defer osrmServer.Close()
+	var messages atomic.Int32
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		messages.Add(1)
+	}))
+	defer webhookServer.Close()
+
+	r := newRunner(context.Background(), nil)
+	defer r.close()
+	defer r.stop()
+	if err := r.apply(testConfig(osrmServer.URL, webhookServer.URL)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.evaluators[1].Evaluate(context.Background())
+
+	// when
+	reloaded := testConfig(osrmServer.URL, webhookServer.URL)
+	reloaded.Rules[0].TravelTime.NotificationThresholdMinutes = 35
+	if err := r.apply(reloaded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.evaluators[1].Evaluate(context.Background())
+
+	// then
+	if count := messages.Load(); count != 2 {
+		t.Errorf("expected the delay to be notified again against the new threshold, got %d messages", count)
+	}
+}
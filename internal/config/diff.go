@@ -0,0 +1,53 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+)
+
+// RuleChanges lists the rules which differ between two configs, matched by rule ID
+type RuleChanges struct {
+	Added   []Rule
+	Removed []Rule
+	Changed []Rule // As they are in the new config
+}
+
+// DiffRules compares the rules of two configs. A rule also counts as changed if the settings it depends on changed,
+// i.e. the routing providers or the SMTP server used by its email channels.
+func DiffRules(old *Config, new *Config) RuleChanges {
+	var changes RuleChanges
+	oldRules := make(map[int]Rule)
+	if old != nil {
+		for _, rule := range old.Rules {
+			oldRules[rule.Id] = rule
+		}
+	}
+
+	newRuleIds := make(map[int]bool)
+	for _, rule := range new.Rules {
+		newRuleIds[rule.Id] = true
+		oldRule, ok := oldRules[rule.Id]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, rule)
+		case !reflect.DeepEqual(oldRule, rule),
+			old.Routing != new.Routing,
+			usesEmail(rule) && old.Smtp != new.Smtp:
+			changes.Changed = append(changes.Changed, rule)
+		}
+	}
+	if old != nil {
+		for _, rule := range old.Rules {
+			if !newRuleIds[rule.Id] {
+				changes.Removed = append(changes.Removed, rule)
+			}
+		}
+	}
+	return changes
+}
+
+func usesEmail(rule Rule) bool {
+	return slices.ContainsFunc(rule.NotificationChannels(), func(channel Channel) bool {
+		return channel.Type == ChannelEmail
+	})
+}
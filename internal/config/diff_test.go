@@ -0,0 +1,110 @@
+package config
+
+import (
+	"testing"
+)
+
+func ruleIds(rules []Rule) []int {
+	ids := make([]int, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.Id)
+	}
+	return ids
+}
+
+func TestDiffRules_AddedRemovedAndChanged(t *testing.T) {
+	// given
+	old := validConfig()
+	old.Rules = append(old.Rules, old.Rules[0], old.Rules[0])
+	old.Rules[1].Id = 2
+	old.Rules[2].Id = 3
+
+	new := validConfig()
+	new.Rules = append(new.Rules, old.Rules[1], old.Rules[0])
+	new.Rules[1].TravelTime.NotificationThresholdMinutes = 20 // Rule 2 changed
+	new.Rules[2].Id = 4                                       // Rule 3 removed, rule 4 added
+
+	// when
+	changes := DiffRules(&old, &new)
+
+	// then
+	if ids := ruleIds(changes.Added); len(ids) != 1 || ids[0] != 4 {
+		t.Errorf("expected rule 4 to be added, got %v", ids)
+	}
+	if ids := ruleIds(changes.Removed); len(ids) != 1 || ids[0] != 3 {
+		t.Errorf("expected rule 3 to be removed, got %v", ids)
+	}
+	if ids := ruleIds(changes.Changed); len(ids) != 1 || ids[0] != 2 {
+		t.Errorf("expected rule 2 to be changed, got %v", ids)
+	}
+	if changes.Changed[0].TravelTime.NotificationThresholdMinutes != 20 {
+		t.Errorf("expected changed rule from the new config, got %+v", changes.Changed[0])
+	}
+}
+
+func TestDiffRules_NoPreviousConfig(t *testing.T) {
+	// given
+	new := validConfig()
+
+	// when
+	changes := DiffRules(nil, &new)
+
+	// then
+	if ids := ruleIds(changes.Added); len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("expected rule 1 to be added, got %v", ids)
+	}
+	if len(changes.Removed) != 0 || len(changes.Changed) != 0 {
+		t.Errorf("expected no removed or changed rules, got %+v", changes)
+	}
+}
+
+func TestDiffRules_SharedSettingsChanged(t *testing.T) {
+	// given
+	old := validConfig()
+	old.Rules = append(old.Rules, old.Rules[0])
+	old.Rules[1].Id = 2
+	old.Rules[1].User = User{Channels: []Channel{{Type: ChannelEmail, To: "commuter@example.com"}}}
+	old.Smtp = Smtp{Host: "smtp.example.com"}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		changed []int
+	}{
+		{
+			name:    "unchanged",
+			modify:  func(cfg *Config) {},
+			changed: []int{},
+		},
+		{
+			name:    "smtp server only affects email rules",
+			modify:  func(cfg *Config) { cfg.Smtp.Port = 465 },
+			changed: []int{2},
+		},
+		{
+			name:    "routing settings affect all rules",
+			modify:  func(cfg *Config) { cfg.Routing.Provider = ProviderOsrm },
+			changed: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			new := old
+			new.Rules = append([]Rule(nil), old.Rules...)
+			tt.modify(&new)
+
+			changes := DiffRules(&old, &new)
+
+			ids := ruleIds(changes.Changed)
+			if len(ids) != len(tt.changed) {
+				t.Fatalf("expected changed rules %v, got %v", tt.changed, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.changed[i] {
+					t.Errorf("expected changed rules %v, got %v", tt.changed, ids)
+				}
+			}
+		})
+	}
+}
@@ -158,6 +158,170 @@ rules:
 	}
 }
 
+func TestLoadConfig_RoutingProviders(t *testing.T) {
+	// Given
+	validYAML := `
+routing:
+  provider: opentripplanner
+  opentripplanner:
+    url: http://localhost:8080/otp/gtfs/v1
+rules:
+  - id: 1
+    origin:
+      name: 10 Downing Street
+      longitude: -0.1276
+      latitude: 51.503
+    destination:
+      name: Palace of Westminster
+      longitude: -0.1246
+      latitude: 51.498
+    user:
+      telegram_user_id: 444455555
+    travel_time:
+      notification_threshold_minutes: 8
+    times:
+      - day: MONDAY
+        time: 08:00
+    timezone: Europe/London
+  - id: 2
+    origin:
+      name: 10 Downing Street
+      longitude: -0.1276
+      latitude: 51.503
+    destination:
+      name: Palace of Westminster
+      longitude: -0.1246
+      latitude: 51.498
+    user:
+      telegram_user_id: 444455555
+    travel_time:
+      notification_threshold_minutes: 8
+    times:
+      - day: MONDAY
+        time: 08:00
+    timezone: Europe/London
+    provider: google
+`
+	file := writeToFile(t, validYAML)
+	defer removeFile(t, file)
+
+	// When
+	actual, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("Error loading config: %s", err)
+	}
+
+	// Then
+	if actual.Routing.OpenTripPlanner.Url != "http://localhost:8080/otp/gtfs/v1" {
+		t.Errorf("Unexpected OpenTripPlanner url: '%s'", actual.Routing.OpenTripPlanner.Url)
+	}
+	if provider := actual.ProviderFor(actual.Rules[0]); provider != ProviderOpenTripPlanner {
+		t.Errorf("Expected rule 1 to use the default provider, got '%s'", provider)
+	}
+	if provider := actual.ProviderFor(actual.Rules[1]); provider != ProviderGoogle {
+		t.Errorf("Expected rule 2 to override the provider, got '%s'", provider)
+	}
+}
+
+func TestLoadConfig_NotificationChannels(t *testing.T) {
+	// Given
+	validYAML := `
+rules:
+  - id: 1
+    origin:
+      name: 10 Downing Street
+      longitude: -0.1276
+      latitude: 51.503
+    destination:
+      name: Palace of Westminster
+      longitude: -0.1246
+      latitude: 51.498
+    user:
+      telegram_user_id: 444455555
+      channels:
+        - type: slack
+          webhook_url: https://hooks.slack.com/services/T000/B000/XXXX
+        - type: webhook
+          webhook_url: https://example.com/hook
+          body_template: '{"message": {{json .Text}}}'
+    travel_time:
+      notification_threshold_minutes: 8
+    times:
+      - day: MONDAY
+        time: 08:00
+    timezone: Europe/London
+`
+	file := writeToFile(t, validYAML)
+	defer removeFile(t, file)
+
+	// When
+	actual, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("Error loading config: %s", err)
+	}
+
+	// Then
+	expected := []Channel{
+		{Type: ChannelTelegram, TelegramUserID: 444455555},
+		{Type: ChannelSlack, WebhookUrl: "https://hooks.slack.com/services/T000/B000/XXXX"},
+		{Type: ChannelWebhook, WebhookUrl: "https://example.com/hook", BodyTemplate: `{"message": {{json .Text}}}`},
+	}
+	if !reflect.DeepEqual(expected, actual.Rules[0].User.NotificationChannels()) {
+		t.Fatalf("Expected: %+v\nGot: %+v", expected, actual.Rules[0].User.NotificationChannels())
+	}
+	if !actual.UsesChannel(ChannelTelegram) || actual.UsesChannel(ChannelDiscord) {
+		t.Errorf("Expected telegram to be used and discord not to be used")
+	}
+}
+
+func TestLoadConfig_Waypoints(t *testing.T) {
+	// Given
+	validYAML := `
+rules:
+  - id: 1
+    origin:
+      name: Home
+      longitude: -0.1276
+      latitude: 51.503
+    waypoints:
+      - name: Nursery
+        longitude: -0.1301
+        latitude: 51.507
+        travel_mode: DRIVE
+        dwell_minutes: 5
+    destination:
+      name: Office
+      longitude: -0.0877
+      latitude: 51.513
+    user:
+      telegram_user_id: 444455555
+    travel_time:
+      notification_threshold_minutes: 60
+    times:
+      - day: MONDAY
+        time: 08:00
+    timezone: Europe/London
+`
+	file := writeToFile(t, validYAML)
+	defer removeFile(t, file)
+
+	// When
+	actual, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("Error loading config: %s", err)
+	}
+
+	// Then
+	expected := []Waypoint{{
+		Location:     Location{Name: "Nursery", Longitude: -0.1301, Latitude: 51.507},
+		TravelMode:   "DRIVE",
+		DwellMinutes: 5,
+	}}
+	if !reflect.DeepEqual(expected, actual.Rules[0].Waypoints) {
+		t.Fatalf("Expected: %+v\nGot: %+v", expected, actual.Rules[0].Waypoints)
+	}
+}
+
 func TestLoadConfig_FileNotFound(t *testing.T) {
 	_, err := LoadConfig("non_existent_file.yaml")
 	if err == nil {
@@ -1,41 +1,271 @@
 package config
 
-// Location defines coordinates and name
+import "slices"
+
+const (
+	ProviderGoogle          = "google"
+	ProviderOpenTripPlanner = "opentripplanner"
+	ProviderOsrm            = "osrm"
+	ProviderValhalla        = "valhalla"
+)
+
+const (
+	ChannelTelegram = "telegram"
+	ChannelSlack    = "slack"
+	ChannelDiscord  = "discord"
+	ChannelWebhook  = "webhook"
+	ChannelEmail    = "email"
+	ChannelNtfy     = "ntfy"
+	ChannelGotify   = "gotify"
+	ChannelPushover = "pushover"
+)
+
+// Location defines a name and either coordinates, an address or a Google place ID
 type Location struct {
 	Name      string  `yaml:"name"`
 	Longitude float64 `yaml:"longitude"`
 	Latitude  float64 `yaml:"latitude"`
+	Address   string  `yaml:"address"`  // e.g. "Westminster, London SW1A 0AA"
+	PlaceID   string  `yaml:"place_id"` // e.g. "ChIJJ5kaocQEdkgRKtmiHtu0p1M"
+}
+
+// HasCoordinates returns whether the latitude and longitude are set, rather than an address or place ID
+func (l Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// Waypoint is an intermediate stop of a multi-leg journey, e.g. a nursery on the way to the office
+type Waypoint struct {
+	Location     `yaml:",inline"`
+	TravelMode   string `yaml:"travel_mode"`   // Optional, travel mode of the leg to this stop, defaults to the rule's
+	DwellMinutes int    `yaml:"dwell_minutes"` // Optional, time spent at the stop before the next leg
+}
+
+// RouteModifiers defines features of the road network to avoid when driving
+type RouteModifiers struct {
+	AvoidTolls    bool `yaml:"avoid_tolls"`
+	AvoidHighways bool `yaml:"avoid_highways"`
+	AvoidFerries  bool `yaml:"avoid_ferries"`
+}
+
+// TransitPreferences restricts the transit journeys considered
+type TransitPreferences struct {
+	AllowedModes      []string `yaml:"allowed_modes"`      // Optional: BUS, SUBWAY, TRAIN, LIGHT_RAIL or RAIL, defaults to all
+	RoutingPreference string   `yaml:"routing_preference"` // Optional: LESS_WALKING or FEWER_TRANSFERS
+}
+
+// Channel defines one way of notifying a user
+type Channel struct {
+	Type           string `yaml:"type"`             // telegram, slack, discord, webhook, email, ntfy, gotify or pushover
+	TelegramUserID int64  `yaml:"telegram_user_id"` // telegram only
+	WebhookUrl     string `yaml:"webhook_url"`      // slack, discord and webhook only
+	BodyTemplate   string `yaml:"body_template"`    // Optional, webhook only
+	From           string `yaml:"from"`             // email only
+	To             string `yaml:"to"`               // email only
+	Url            string `yaml:"url"`              // ntfy topic or gotify server
+	Token          string `yaml:"token"`            // gotify and pushover app token, optional ntfy access token
+	UserKey        string `yaml:"user_key"`         // pushover only
 }
 
 // User defines the user receiving notifications
 type User struct {
-	TelegramUserID int64 `yaml:"telegram_user_id"`
+	TelegramUserID int64     `yaml:"telegram_user_id"` // Optional, shorthand for a telegram channel
+	Channels       []Channel `yaml:"channels"`
 }
 
-// TravelTime defines the notification threshold
+// NotificationChannels returns all channels of the user, including the telegram_user_id shorthand
+func (u User) NotificationChannels() []Channel {
+	channels := make([]Channel, 0, len(u.Channels)+1)
+	if u.TelegramUserID != 0 {
+		channels = append(channels, Channel{Type: ChannelTelegram, TelegramUserID: u.TelegramUserID})
+	}
+	return append(channels, u.Channels...)
+}
+
+// TravelTime defines the notification threshold, either absolute or relative to a baseline, or several levels
 type TravelTime struct {
-	NotificationThresholdMinutes int `yaml:"notification_threshold_minutes"`
+	NotificationThresholdMinutes int      `yaml:"notification_threshold_minutes"`
+	MinutesAboveBaseline         int      `yaml:"minutes_above_baseline"` // e.g. 10 to notify when 10 minutes slower than usual
+	PercentAboveBaseline         int      `yaml:"percent_above_baseline"` // e.g. 25 to notify when 25% slower than usual
+	Baseline                     Baseline `yaml:"baseline"`               // Required by relative thresholds
+	Levels                       []Level  `yaml:"levels"`                 // Optional, ordered by threshold, replaces the threshold above
+}
+
+// Level is one of several severity levels of a rule, e.g. "Heads up" at +10 minutes and "Work from home" at +25
+type Level struct {
+	Label                        string    `yaml:"label"`
+	NotificationThresholdMinutes int       `yaml:"notification_threshold_minutes"`
+	MinutesAboveBaseline         int       `yaml:"minutes_above_baseline"`
+	PercentAboveBaseline         int       `yaml:"percent_above_baseline"`
+	Message                      string    `yaml:"message"`  // Optional text/template, e.g. "{{.Label}}: leave by car"
+	Channels                     []Channel `yaml:"channels"` // Optional, notified instead of the user's channels
 }
 
-// TimeSchedule defines a time and day pair
+// IsRelative returns whether the threshold is relative to a baseline
+func (t TravelTime) IsRelative() bool {
+	return slices.ContainsFunc(t.AllLevels(), Level.IsRelative)
+}
+
+// AllLevels returns the levels, or a single unlabelled level for a rule with just one threshold
+func (t TravelTime) AllLevels() []Level {
+	if len(t.Levels) > 0 {
+		return t.Levels
+	}
+	return []Level{{
+		NotificationThresholdMinutes: t.NotificationThresholdMinutes,
+		MinutesAboveBaseline:         t.MinutesAboveBaseline,
+		PercentAboveBaseline:         t.PercentAboveBaseline,
+	}}
+}
+
+// IsRelative returns whether the threshold of the level is relative to a baseline
+func (l Level) IsRelative() bool {
+	return l.MinutesAboveBaseline > 0 || l.PercentAboveBaseline > 0
+}
+
+// Baseline defines the usual travel time, either fixed or computed from the history
+type Baseline struct {
+	Minutes        int `yaml:"minutes"`         // Fixed baseline
+	HistorySamples int `yaml:"history_samples"` // Median of the last N weeks on the same weekday and time slot, one sample each
+}
+
+// TimeSchedule defines either a time and day pair, a window of repeated times on several days, or a cron expression
 type TimeSchedule struct {
-	Day  string `yaml:"day"`
-	Time string `yaml:"time"`
+	Day   string   `yaml:"day"`
+	Time  string   `yaml:"time"`
+	Days  []string `yaml:"days"`  // Window only
+	From  string   `yaml:"from"`  // Window only, e.g. "07:00"
+	To    string   `yaml:"to"`    // Window only, inclusive, e.g. "08:30"
+	Every string   `yaml:"every"` // Window only, e.g. "10m"
+	Cron  string   `yaml:"cron"`  // e.g. "*/15 7-9 * * MON-FRI"
+}
+
+// IsWindow returns whether the schedule repeats between two times rather than running once per day
+func (t TimeSchedule) IsWindow() bool {
+	return len(t.Days) > 0 || t.From != "" || t.To != "" || t.Every != ""
 }
 
 // Rule represents one travel rule
 type Rule struct {
-	Id          int            `yaml:"id"`
-	Origin      Location       `yaml:"origin"`
-	Destination Location       `yaml:"destination"`
-	User        User           `yaml:"user"`
-	TravelTime  TravelTime     `yaml:"travel_time"`
-	Times       []TimeSchedule `yaml:"times"`
-	Timezone    string         `yaml:"timezone"`
-	Holidays    []string       `yaml:"holidays"`
+	Id                 int                `yaml:"id"`
+	Origin             Location           `yaml:"origin"`
+	Destination        Location           `yaml:"destination"`
+	Waypoints          []Waypoint         `yaml:"waypoints"` // Optional, the last leg to the destination uses the rule's travel mode
+	User               User               `yaml:"user"`
+	TravelTime         TravelTime         `yaml:"travel_time"`
+	Times              []TimeSchedule     `yaml:"times"`
+	Timezone           string             `yaml:"timezone"`
+	Holidays           []string           `yaml:"holidays"`
+	TravelMode         string             `yaml:"travel_mode"`         // Optional, defaults to TRANSIT
+	CompareModes       []string           `yaml:"compare_modes"`       // Optional, alternative travel modes included in delay notifications
+	AlternativeRoutes  bool               `yaml:"alternative_routes"`  // Optional, evaluates the fastest of alternative routes, google only
+	RouteModifiers     RouteModifiers     `yaml:"route_modifiers"`     // Optional, DRIVE and TWO_WHEELER only, google only
+	TransitPreferences TransitPreferences `yaml:"transit_preferences"` // Optional, TRANSIT only, google only
+	DepartureTime      string             `yaml:"departure_time"`      // Optional, e.g. "08:30", defaults to now
+	ArrivalTime        string             `yaml:"arrival_time"`        // Optional, e.g. "09:00", TRANSIT only
+	Provider           string             `yaml:"provider"`            // Optional, overrides the routing provider
+}
+
+// Locations returns the origin, waypoints and destination of the rule, which can be updated through the pointers
+func (r *Rule) Locations() []*Location {
+	locations := []*Location{&r.Origin}
+	for i := range r.Waypoints {
+		locations = append(locations, &r.Waypoints[i].Location)
+	}
+	return append(locations, &r.Destination)
+}
+
+// NotificationChannels returns the channels of the user and of all levels
+func (r Rule) NotificationChannels() []Channel {
+	channels := r.User.NotificationChannels()
+	for _, level := range r.TravelTime.Levels {
+		channels = append(channels, level.Channels...)
+	}
+	return channels
+}
+
+// OpenTripPlanner defines a self-hosted OpenTripPlanner instance
+type OpenTripPlanner struct {
+	Url string `yaml:"url"` // GraphQL endpoint, e.g. http://localhost:8080/otp/gtfs/v1
+}
+
+// Osrm defines a self-hosted OSRM server
+type Osrm struct {
+	Url string `yaml:"url"` // e.g. http://localhost:5000
+}
+
+// Valhalla defines a self-hosted Valhalla server
+type Valhalla struct {
+	Url string `yaml:"url"` // e.g. http://localhost:8002
+}
+
+// Google defines the Google Maps Platform provider, the API key is read from the environment
+type Google struct {
+	Geocode     bool   `yaml:"geocode"`      // Optional, resolves addresses and place IDs to coordinates when the config is loaded
+	Batch       bool   `yaml:"batch"`        // Optional, combines requests made at the same time into route matrix requests
+	Timeout     string `yaml:"timeout"`      // Optional, e.g. "10s" (default) for each attempt at a request
+	MaxAttempts int    `yaml:"max_attempts"` // Optional, defaults to 3, transient errors are retried with backoff
+}
+
+// Routing defines the routing providers used to compute journeys
+type Routing struct {
+	Provider        string          `yaml:"provider"`  // Optional, defaults to google
+	CacheTTL        string          `yaml:"cache_ttl"` // Optional, e.g. "2m" to reuse journeys of identical requests
+	Google          Google          `yaml:"google"`
+	OpenTripPlanner OpenTripPlanner `yaml:"opentripplanner"`
+	Osrm            Osrm            `yaml:"osrm"`
+	Valhalla        Valhalla        `yaml:"valhalla"`
+}
+
+// Smtp defines the mail server used by email channels, credentials are read from the environment
+type Smtp struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`     // Optional, defaults to 587
+	Security string `yaml:"security"` // Optional: starttls (default), tls or none
+}
+
+// History defines where the result of each evaluation is stored
+type History struct {
+	Path string `yaml:"path"` // Optional, e.g. /data/history.db, history is not stored if empty
 }
 
 // Config represents the full configuration
 type Config struct {
-	Rules []Rule `yaml:"rules"`
+	Routing Routing `yaml:"routing"`
+	Smtp    Smtp    `yaml:"smtp"`
+	History History `yaml:"history"`
+	Rules   []Rule  `yaml:"rules"`
+}
+
+// UsesChannel returns whether any rule notifies its user over the channel type
+func (cfg *Config) UsesChannel(channelType string) bool {
+	for _, rule := range cfg.Rules {
+		for _, channel := range rule.NotificationChannels() {
+			if channel.Type == channelType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UsesProvider returns whether any rule uses the routing provider
+func (cfg *Config) UsesProvider(provider string) bool {
+	for _, rule := range cfg.Rules {
+		if cfg.ProviderFor(rule) == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// ProviderFor returns the name of the routing provider used by the rule
+func (cfg *Config) ProviderFor(rule Rule) string {
+	if rule.Provider != "" {
+		return rule.Provider
+	}
+	if cfg.Routing.Provider != "" {
+		return cfg.Routing.Provider
+	}
+	return ProviderGoogle
 }
@@ -2,15 +2,50 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"text/template"
 	"time"
+	"wayfarer/internal/scheduling"
 )
 
 func (cfg *Config) validate() error {
+	// Check the default routing provider
+	if cfg.Routing.Provider != "" && !providers[cfg.Routing.Provider] {
+		return errInvalidProvider
+	}
+	if cfg.Routing.CacheTTL != "" {
+		if ttl, err := time.ParseDuration(cfg.Routing.CacheTTL); err != nil || ttl <= 0 {
+			return errors.New("routing cache_ttl must be a positive duration, e.g. 2m")
+		}
+	}
+	if cfg.Routing.Google.Timeout != "" {
+		if timeout, err := time.ParseDuration(cfg.Routing.Google.Timeout); err != nil || timeout <= 0 {
+			return errors.New("google timeout must be a positive duration, e.g. 10s")
+		}
+	}
+	if cfg.Routing.Google.MaxAttempts < 0 {
+		return errors.New("google max_attempts must not be negative")
+	}
+
+	// Check the mail server
+	if cfg.Smtp.Security != "" && !smtpSecurity[cfg.Smtp.Security] {
+		return errors.New("smtp security must be one of starttls, tls or none")
+	}
+	if cfg.UsesChannel(ChannelEmail) && cfg.Smtp.Host == "" {
+		return errors.New("smtp host must be specified to use email channels")
+	}
+
+	ruleIds := make(map[int]bool)
 	for _, rule := range cfg.Rules {
-		// Check ID
+		// Check ID, which identifies the rule when the config is reloaded
 		if rule.Id <= 0 {
 			return errors.New("id must be greater than 0")
 		}
+		if ruleIds[rule.Id] {
+			return fmt.Errorf("id %d is used by more than one rule", rule.Id)
+		}
+		ruleIds[rule.Id] = true
 
 		// Check if Origin and Destination are defined
 		if rule.Origin.Name == "" || rule.Destination.Name == "" {
@@ -18,13 +53,18 @@ func (cfg *Config) validate() error {
 		}
 
 		// Check if User is defined
-		if rule.User.TelegramUserID == 0 {
-			return errors.New("user must have a Telegram user ID")
+		if len(rule.User.NotificationChannels()) == 0 {
+			return errors.New("user must have a Telegram user ID or at least one channel")
+		}
+		for _, channel := range rule.User.Channels {
+			if err := channel.validate(); err != nil {
+				return err
+			}
 		}
 
 		// Ensure TravelTime is specified
-		if rule.TravelTime.NotificationThresholdMinutes <= 0 {
-			return errors.New("notification_threshold_minutes must be greater than 0")
+		if err := cfg.validateTravelTime(rule.TravelTime); err != nil {
+			return err
 		}
 
 		// Ensure Times are not empty
@@ -34,6 +74,19 @@ func (cfg *Config) validate() error {
 
 		// validate each time schedule
 		for _, t := range rule.Times {
+			if t.Cron != "" {
+				if err := t.validateCron(); err != nil {
+					return err
+				}
+				continue
+			}
+			if t.IsWindow() {
+				if err := t.validateWindow(); err != nil {
+					return err
+				}
+				continue
+			}
+
 			// validate day
 			if _, err := ParseWeekday(t.Day); err != nil {
 				return err
@@ -45,6 +98,86 @@ func (cfg *Config) validate() error {
 			}
 		}
 
+		// validate travel mode
+		if rule.TravelMode != "" && !travelModes[rule.TravelMode] {
+			return errInvalidTravelMode
+		}
+
+		// validate departure and arrival times
+		if rule.DepartureTime != "" && rule.ArrivalTime != "" {
+			return errors.New("only one of departure_time and arrival_time may be specified")
+		}
+		if rule.DepartureTime != "" {
+			if _, err := time.Parse("15:04", rule.DepartureTime); err != nil {
+				return errInvalidTimeFormat
+			}
+		}
+		if rule.ArrivalTime != "" {
+			if _, err := time.Parse("15:04", rule.ArrivalTime); err != nil {
+				return errInvalidTimeFormat
+			}
+			if rule.TravelMode != "" && rule.TravelMode != "TRANSIT" {
+				return errors.New("arrival_time is only supported for the TRANSIT travel mode")
+			}
+		}
+
+		// validate routing provider
+		if rule.Provider != "" && !providers[rule.Provider] {
+			return errInvalidProvider
+		}
+		provider := cfg.ProviderFor(rule)
+		if url, selfHosted := cfg.Routing.serverUrl(provider); selfHosted && url == "" {
+			return fmt.Errorf("%s url must be specified to use the %s provider", provider, provider)
+		}
+		travelMode := rule.TravelMode
+		if travelMode == "" {
+			travelMode = "TRANSIT"
+		}
+		if slices.Contains(unsupportedTravelModes[provider], travelMode) {
+			return fmt.Errorf("the %s provider does not support the %s travel mode", provider, travelMode)
+		}
+
+		// validate travel modes to compare with
+		for _, mode := range rule.CompareModes {
+			if !travelModes[mode] {
+				return errInvalidTravelMode
+			}
+			if mode == travelMode {
+				return errors.New("compare_modes must not include the travel mode of the rule")
+			}
+			if slices.Contains(unsupportedTravelModes[provider], mode) {
+				return fmt.Errorf("the %s provider does not support the %s travel mode", provider, mode)
+			}
+		}
+		if len(rule.CompareModes) > 0 && rule.ArrivalTime != "" {
+			return errors.New("compare_modes can't be used with arrival_time, which is only supported for the TRANSIT travel mode")
+		}
+		if rule.AlternativeRoutes && provider != ProviderGoogle {
+			return errors.New("alternative_routes is only supported by the google provider")
+		}
+
+		// validate intermediate waypoints
+		for _, waypoint := range rule.Waypoints {
+			if err := waypoint.validate(provider); err != nil {
+				return err
+			}
+		}
+		if len(rule.Waypoints) > 0 && (rule.ArrivalTime != "" || len(rule.CompareModes) > 0 || rule.AlternativeRoutes) {
+			return errors.New("waypoints can't be used with arrival_time, compare_modes or alternative_routes")
+		}
+
+		// validate route modifiers and transit preferences
+		if err := rule.validatePreferences(provider, travelMode); err != nil {
+			return err
+		}
+
+		// validate how locations are given
+		for _, location := range rule.Locations() {
+			if err := cfg.validateLocation(*location, provider); err != nil {
+				return err
+			}
+		}
+
 		// validate timezone
 		if _, err := time.LoadLocation(rule.Timezone); err != nil {
 			return err
@@ -55,4 +188,299 @@ func (cfg *Config) validate() error {
 	return nil
 }
 
+var travelModes = map[string]bool{
+	"DRIVE":       true,
+	"TRANSIT":     true,
+	"BICYCLE":     true,
+	"WALK":        true,
+	"TWO_WHEELER": true,
+}
+
+var transitModes = map[string]bool{
+	"BUS":        true,
+	"SUBWAY":     true,
+	"TRAIN":      true,
+	"LIGHT_RAIL": true,
+	"RAIL":       true,
+}
+
+var transitRoutingPreferences = map[string]bool{
+	"LESS_WALKING":    true,
+	"FEWER_TRANSFERS": true,
+}
+
+var providers = map[string]bool{
+	ProviderGoogle:          true,
+	ProviderOpenTripPlanner: true,
+	ProviderOsrm:            true,
+	ProviderValhalla:        true,
+}
+
+var smtpSecurity = map[string]bool{
+	"starttls": true,
+	"tls":      true,
+	"none":     true,
+}
+
+var unsupportedTravelModes = map[string][]string{
+	ProviderOpenTripPlanner: {"TWO_WHEELER"},
+	ProviderOsrm:            {"TRANSIT", "TWO_WHEELER"},
+}
+
+func (cfg *Config) validateLocation(location Location, provider string) error {
+	set := 0
+	for _, isSet := range []bool{location.HasCoordinates(), location.Address != "", location.PlaceID != ""} {
+		if isSet {
+			set++
+		}
+	}
+	if set == 0 {
+		return fmt.Errorf("%s must have coordinates, an address or a place_id", location.Name)
+	}
+	if set > 1 {
+		return fmt.Errorf("%s must have only one of coordinates, an address and a place_id", location.Name)
+	}
+	if !location.HasCoordinates() && provider != ProviderGoogle && !cfg.Routing.Google.Geocode {
+		return fmt.Errorf("the %s provider needs coordinates for %s, unless addresses and place IDs are geocoded", provider, location.Name)
+	}
+	return nil
+}
+
+// validatePreferences checks the route modifiers and transit preferences apply to at least one travel mode of the rule
+func (r Rule) validatePreferences(provider string, travelMode string) error {
+	hasModifiers := r.RouteModifiers != RouteModifiers{}
+	hasPreferences := len(r.TransitPreferences.AllowedModes) > 0 || r.TransitPreferences.RoutingPreference != ""
+	if !hasModifiers && !hasPreferences {
+		return nil
+	}
+	if provider != ProviderGoogle {
+		return errors.New("route_modifiers and transit_preferences are only supported by the google provider")
+	}
+
+	modes := append([]string{travelMode}, r.CompareModes...)
+	for _, waypoint := range r.Waypoints {
+		modes = append(modes, waypoint.TravelMode)
+	}
+	if hasModifiers && !slices.Contains(modes, "DRIVE") && !slices.Contains(modes, "TWO_WHEELER") {
+		return errors.New("route_modifiers only apply to the DRIVE and TWO_WHEELER travel modes")
+	}
+	if hasPreferences && !slices.Contains(modes, "TRANSIT") {
+		return errors.New("transit_preferences only apply to the TRANSIT travel mode")
+	}
+	for _, mode := range r.TransitPreferences.AllowedModes {
+		if !transitModes[mode] {
+			return fmt.Errorf("invalid transit mode %s, must be one of BUS, SUBWAY, TRAIN, LIGHT_RAIL or RAIL", mode)
+		}
+	}
+	if preference := r.TransitPreferences.RoutingPreference; preference != "" && !transitRoutingPreferences[preference] {
+		return errors.New("transit routing_preference must be LESS_WALKING or FEWER_TRANSFERS")
+	}
+	return nil
+}
+
+func (w Waypoint) validate(provider string) error {
+	if w.Name == "" {
+		return errors.New("waypoints must have a name")
+	}
+	if w.TravelMode != "" && !travelModes[w.TravelMode] {
+		return errInvalidTravelMode
+	}
+	if slices.Contains(unsupportedTravelModes[provider], w.TravelMode) {
+		return fmt.Errorf("the %s provider does not support the %s travel mode", provider, w.TravelMode)
+	}
+	if w.DwellMinutes < 0 {
+		return errors.New("dwell_minutes must not be negative")
+	}
+	return nil
+}
+
+func (cfg *Config) validateTravelTime(travelTime TravelTime) error {
+	if len(travelTime.Levels) == 0 {
+		if err := travelTime.AllLevels()[0].validateThreshold(); err != nil {
+			return err
+		}
+	} else if err := travelTime.validateLevels(); err != nil {
+		return err
+	}
+
+	if !travelTime.IsRelative() {
+		return nil
+	}
+	baseline := travelTime.Baseline
+	if baseline.Minutes < 0 || baseline.HistorySamples < 0 {
+		return errors.New("baseline minutes and history_samples must be greater than 0")
+	}
+	if (baseline.Minutes > 0) == (baseline.HistorySamples > 0) {
+		return errors.New("a relative threshold needs a baseline with either minutes or history_samples")
+	}
+	if baseline.HistorySamples > 0 && cfg.History.Path == "" {
+		return errors.New("history path must be specified to use a baseline from history")
+	}
+	return nil
+}
+
+func (l Level) validateThreshold() error {
+	if !l.IsRelative() {
+		if l.NotificationThresholdMinutes <= 0 {
+			return errors.New("notification_threshold_minutes must be greater than 0")
+		}
+		return nil
+	}
+	if l.NotificationThresholdMinutes != 0 || (l.MinutesAboveBaseline != 0 && l.PercentAboveBaseline != 0) {
+		return errors.New("only one of notification_threshold_minutes, minutes_above_baseline and percent_above_baseline may be specified")
+	}
+	if l.MinutesAboveBaseline < 0 || l.PercentAboveBaseline < 0 {
+		return errors.New("minutes_above_baseline and percent_above_baseline must be greater than 0")
+	}
+	return nil
+}
+
+// threshold returns the kind of threshold of the level and its value
+func (l Level) threshold() (kind string, value int) {
+	switch {
+	case l.MinutesAboveBaseline > 0:
+		return "minutes_above_baseline", l.MinutesAboveBaseline
+	case l.PercentAboveBaseline > 0:
+		return "percent_above_baseline", l.PercentAboveBaseline
+	default:
+		return "notification_threshold_minutes", l.NotificationThresholdMinutes
+	}
+}
+
+func (t TravelTime) validateLevels() error {
+	if t.NotificationThresholdMinutes != 0 || t.MinutesAboveBaseline != 0 || t.PercentAboveBaseline != 0 {
+		return errors.New("levels replace notification_threshold_minutes, minutes_above_baseline and percent_above_baseline")
+	}
+
+	firstKind, _ := t.Levels[0].threshold()
+	previousValue := 0
+	for _, level := range t.Levels {
+		if level.Label == "" {
+			return errors.New("each level must have a label")
+		}
+		if err := level.validateThreshold(); err != nil {
+			return err
+		}
+		kind, value := level.threshold()
+		if kind != firstKind {
+			return errors.New("all levels must use the same kind of threshold")
+		}
+		if value <= previousValue {
+			return errors.New("levels must be ordered by increasing threshold")
+		}
+		previousValue = value
+		if _, err := template.New("message").Parse(level.Message); err != nil {
+			return fmt.Errorf("invalid message template of level %q: %w", level.Label, err)
+		}
+		for _, channel := range level.Channels {
+			if err := channel.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t TimeSchedule) validateCron() error {
+	if t.Day != "" || t.Time != "" || t.IsWindow() {
+		return errors.New("a cron schedule must not also have a day, time or window")
+	}
+	cron, err := scheduling.ParseCron(t.Cron)
+	if err != nil {
+		return err
+	}
+	if _, err := cron.Next(time.Now(), time.UTC); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t TimeSchedule) validateWindow() error {
+	if t.Day != "" || t.Time != "" {
+		return errors.New("a time window must use days, from, to and every instead of day and time")
+	}
+	if len(t.Days) == 0 {
+		return errors.New("a time window must have at least one day")
+	}
+	for _, day := range t.Days {
+		if _, err := ParseWeekday(day); err != nil {
+			return err
+		}
+	}
+
+	from, err := time.Parse("15:04", t.From)
+	if err != nil {
+		return errInvalidTimeFormat
+	}
+	to, err := time.Parse("15:04", t.To)
+	if err != nil {
+		return errInvalidTimeFormat
+	}
+	if to.Before(from) {
+		return errors.New("a time window must end after it starts")
+	}
+
+	every, err := time.ParseDuration(t.Every)
+	if err != nil || every < time.Minute || every%time.Minute != 0 {
+		return errors.New("a time window must repeat every whole number of minutes, e.g. every: 10m")
+	}
+	return nil
+}
+
+func (c Channel) validate() error {
+	switch c.Type {
+	case ChannelTelegram:
+		if c.TelegramUserID == 0 {
+			return errors.New("telegram channel must have a Telegram user ID")
+		}
+	case ChannelSlack, ChannelDiscord, ChannelWebhook:
+		if c.WebhookUrl == "" {
+			return fmt.Errorf("%s channel must have a webhook_url", c.Type)
+		}
+		if c.Type == ChannelWebhook {
+			// Parsed like webhook.NewClient does, the json function only needs to exist to parse
+			bodyTemplate := template.New("body").Funcs(template.FuncMap{"json": func(any) (string, error) { return "", nil }})
+			if _, err := bodyTemplate.Parse(c.BodyTemplate); err != nil {
+				return fmt.Errorf("invalid body_template of webhook channel: %w", err)
+			}
+		}
+	case ChannelEmail:
+		if c.From == "" || c.To == "" {
+			return errors.New("email channel must have from and to addresses")
+		}
+	case ChannelNtfy:
+		if c.Url == "" {
+			return errors.New("ntfy channel must have a topic url")
+		}
+	case ChannelGotify:
+		if c.Url == "" || c.Token == "" {
+			return errors.New("gotify channel must have a url and token")
+		}
+	case ChannelPushover:
+		if c.Token == "" || c.UserKey == "" {
+			return errors.New("pushover channel must have a token and user_key")
+		}
+	default:
+		return errInvalidChannel
+	}
+	return nil
+}
+
+// serverUrl returns the URL of a self-hosted routing provider's server
+func (r Routing) serverUrl(provider string) (url string, selfHosted bool) {
+	switch provider {
+	case ProviderOpenTripPlanner:
+		return r.OpenTripPlanner.Url, true
+	case ProviderOsrm:
+		return r.Osrm.Url, true
+	case ProviderValhalla:
+		return r.Valhalla.Url, true
+	default:
+		return "", false
+	}
+}
+
 var errInvalidTimeFormat = errors.New("invalid time format")
+var errInvalidTravelMode = errors.New("invalid travel mode")
+var errInvalidProvider = errors.New("invalid routing provider")
+var errInvalidChannel = errors.New("invalid notification channel type")
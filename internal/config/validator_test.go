@@ -57,6 +57,16 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "id must be greater than 0",
 		},
+		{
+			name: "duplicate rule id",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules = append(cfg.Rules, cfg.Rules[0])
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "id 1 is used by more than one rule",
+		},
 		{
 			name: "missing origin name",
 			cfg: func() Config {
@@ -87,6 +97,142 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "user must have a Telegram user ID",
 		},
+		{
+			name: "channels instead of telegram user id",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User = User{Channels: []Channel{
+					{Type: ChannelSlack, WebhookUrl: "https://hooks.slack.com/services/T000/B000/XXXX"},
+					{Type: ChannelTelegram, TelegramUserID: 123456789},
+				}}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid channel type",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: "carrier_pigeon"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidChannel.Error(),
+		},
+		{
+			name: "webhook channel without url",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelDiscord}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "discord channel must have a webhook_url",
+		},
+		{
+			name: "webhook channel with invalid body template",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelWebhook, WebhookUrl: "https://example.com/hook", BodyTemplate: `{"message": {{json .Text}`}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "invalid body_template of webhook channel",
+		},
+		{
+			name: "telegram channel without user id",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelTelegram}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "telegram channel must have a Telegram user ID",
+		},
+		{
+			name: "push notification channels",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User = User{Channels: []Channel{
+					{Type: ChannelNtfy, Url: "https://ntfy.sh/my-commute"},
+					{Type: ChannelGotify, Url: "https://gotify.example.com", Token: "APP_TOKEN"},
+					{Type: ChannelPushover, Token: "APP_TOKEN", UserKey: "USER_KEY"},
+				}}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "gotify channel without token",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelGotify, Url: "https://gotify.example.com"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "gotify channel must have a url and token",
+		},
+		{
+			name: "pushover channel without user key",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelPushover, Token: "APP_TOKEN"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "pushover channel must have a token and user_key",
+		},
+		{
+			name: "ntfy channel without url",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelNtfy}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "ntfy channel must have a topic url",
+		},
+		{
+			name: "email channel",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Smtp = Smtp{Host: "smtp.example.com", Port: 465, Security: "tls"}
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelEmail, From: "wayfarer@example.com", To: "commuter@example.com"}}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "email channel without smtp host",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelEmail, From: "wayfarer@example.com", To: "commuter@example.com"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "smtp host must be specified",
+		},
+		{
+			name: "email channel without recipient",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Smtp.Host = "smtp.example.com"
+				cfg.Rules[0].User.Channels = []Channel{{Type: ChannelEmail, From: "wayfarer@example.com"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "email channel must have from and to addresses",
+		},
+		{
+			name: "invalid smtp security",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Smtp = Smtp{Host: "smtp.example.com", Security: "ssl"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "smtp security must be one of starttls, tls or none",
+		},
 		{
 			name: "notification threshold minutes not positive",
 			cfg: func() Config {
@@ -98,34 +244,718 @@ func TestValidateConfig(t *testing.T) {
 			errMsg:  "notification_threshold_minutes must be greater than 0",
 		},
 		{
-			name: "empty times list",
+			name: "percent above fixed baseline",
 			cfg: func() Config {
 				cfg := validConfig()
-				cfg.Rules[0].Times = []TimeSchedule{}
+				cfg.Rules[0].TravelTime = TravelTime{PercentAboveBaseline: 25, Baseline: Baseline{Minutes: 30}}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "minutes above baseline from history",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.History.Path = "/data/history.db"
+				cfg.Rules[0].TravelTime = TravelTime{MinutesAboveBaseline: 10, Baseline: Baseline{HistorySamples: 20}}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "absolute and relative thresholds",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{NotificationThresholdMinutes: 10, PercentAboveBaseline: 25, Baseline: Baseline{Minutes: 30}}
 				return cfg
 			}(),
 			wantErr: true,
-			errMsg:  "at least one time must be specified",
+			errMsg:  "only one of notification_threshold_minutes, minutes_above_baseline and percent_above_baseline",
 		},
 		{
-			name: "invalid weekday",
+			name: "relative threshold without baseline",
 			cfg: func() Config {
 				cfg := validConfig()
-				cfg.Rules[0].Times[0].Day = "FUNDAY"
+				cfg.Rules[0].TravelTime = TravelTime{PercentAboveBaseline: 25}
 				return cfg
 			}(),
 			wantErr: true,
-			errMsg:  errInvalidDay.Error(),
+			errMsg:  "a relative threshold needs a baseline with either minutes or history_samples",
 		},
 		{
-			name: "invalid time format",
+			name: "fixed and history baselines",
 			cfg: func() Config {
 				cfg := validConfig()
-				cfg.Rules[0].Times[0].Time = "9 AM"
+				cfg.History.Path = "/data/history.db"
+				cfg.Rules[0].TravelTime = TravelTime{PercentAboveBaseline: 25, Baseline: Baseline{Minutes: 30, HistorySamples: 20}}
 				return cfg
 			}(),
 			wantErr: true,
-			errMsg:  errInvalidTimeFormat.Error(),
+			errMsg:  "a relative threshold needs a baseline with either minutes or history_samples",
+		},
+		{
+			name: "levels",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{
+					Baseline: Baseline{Minutes: 30},
+					Levels: []Level{
+						{Label: "Heads up", MinutesAboveBaseline: 10},
+						{Label: "Work from home", MinutesAboveBaseline: 25, Message: "{{.Label}}: the journey takes {{.Minutes}} minutes",
+							Channels: []Channel{{Type: ChannelSlack, WebhookUrl: "https://hooks.slack.com/services/..."}}},
+					},
+				}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "levels and threshold",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime.Levels = []Level{{Label: "Heads up", NotificationThresholdMinutes: 20}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "levels replace notification_threshold_minutes",
+		},
+		{
+			name: "level without label",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{Levels: []Level{{NotificationThresholdMinutes: 20}}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "each level must have a label",
+		},
+		{
+			name: "levels not in order",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{Levels: []Level{
+					{Label: "Work from home", NotificationThresholdMinutes: 40},
+					{Label: "Heads up", NotificationThresholdMinutes: 20},
+				}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "levels must be ordered by increasing threshold",
+		},
+		{
+			name: "levels with different kinds of threshold",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{Baseline: Baseline{Minutes: 30}, Levels: []Level{
+					{Label: "Heads up", NotificationThresholdMinutes: 40},
+					{Label: "Work from home", MinutesAboveBaseline: 20},
+				}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "all levels must use the same kind of threshold",
+		},
+		{
+			name: "relative levels without baseline",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{Levels: []Level{{Label: "Heads up", PercentAboveBaseline: 25}}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a relative threshold needs a baseline",
+		},
+		{
+			name: "level with invalid message template",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{Levels: []Level{{Label: "Heads up", NotificationThresholdMinutes: 20, Message: "{{.Label"}}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  `invalid message template of level "Heads up"`,
+		},
+		{
+			name: "level with invalid channel",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{Levels: []Level{{Label: "Heads up", NotificationThresholdMinutes: 20,
+					Channels: []Channel{{Type: ChannelSlack}}}}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "slack channel must have a webhook_url",
+		},
+		{
+			name: "compare travel modes",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].CompareModes = []string{"DRIVE", "BICYCLE"}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid travel mode to compare",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].CompareModes = []string{"HORSE"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTravelMode.Error(),
+		},
+		{
+			name: "compare with the travel mode of the rule",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].CompareModes = []string{"DRIVE"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "compare_modes must not include the travel mode of the rule",
+		},
+		{
+			name: "compare with a travel mode the provider doesn't support",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].Provider = ProviderOsrm
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].CompareModes = []string{"TRANSIT"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "the osrm provider does not support the TRANSIT travel mode",
+		},
+		{
+			name: "compare travel modes with arrival time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].ArrivalTime = "09:00"
+				cfg.Rules[0].CompareModes = []string{"DRIVE"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "compare_modes can't be used with arrival_time",
+		},
+		{
+			name: "alternative routes with a provider other than google",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].Provider = ProviderOsrm
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].AlternativeRoutes = true
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "alternative_routes is only supported by the google provider",
+		},
+		{
+			name: "waypoint without a name",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Waypoints = []Waypoint{{Location: Location{Latitude: 51.507, Longitude: -0.1301}}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "waypoints must have a name",
+		},
+		{
+			name: "waypoint with an invalid travel mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Waypoints = []Waypoint{{Location: Location{Name: "Nursery", Latitude: 51.507, Longitude: -0.1301}, TravelMode: "HOVERCRAFT"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTravelMode.Error(),
+		},
+		{
+			name: "waypoint with a travel mode the provider doesn't support",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].Provider = ProviderOsrm
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].Waypoints = []Waypoint{{Location: Location{Name: "Nursery", Latitude: 51.507, Longitude: -0.1301}, TravelMode: "TRANSIT"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "the osrm provider does not support the TRANSIT travel mode",
+		},
+		{
+			name: "waypoint with a negative dwell time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Waypoints = []Waypoint{{Location: Location{Name: "Nursery", Latitude: 51.507, Longitude: -0.1301}, DwellMinutes: -5}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "dwell_minutes must not be negative",
+		},
+		{
+			name: "waypoints with arrival time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].ArrivalTime = "09:00"
+				cfg.Rules[0].Waypoints = []Waypoint{{Location: Location{Name: "Nursery", Latitude: 51.507, Longitude: -0.1301}, TravelMode: "DRIVE"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "waypoints can't be used with arrival_time, compare_modes or alternative_routes",
+		},
+		{
+			name: "routing cache ttl",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.CacheTTL = "2m"
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid routing cache ttl",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.CacheTTL = "2 minutes"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "routing cache_ttl must be a positive duration",
+		},
+		{
+			name: "invalid google timeout",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Google.Timeout = "-5s"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "google timeout must be a positive duration",
+		},
+		{
+			name: "negative google max attempts",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Google.MaxAttempts = -1
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "google max_attempts must not be negative",
+		},
+		{
+			name: "route modifiers and transit preferences",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].CompareModes = []string{"DRIVE"}
+				cfg.Rules[0].RouteModifiers = RouteModifiers{AvoidTolls: true, AvoidFerries: true}
+				cfg.Rules[0].TransitPreferences = TransitPreferences{AllowedModes: []string{"SUBWAY", "TRAIN"}, RoutingPreference: "FEWER_TRANSFERS"}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "route modifiers without a driving travel mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].RouteModifiers = RouteModifiers{AvoidHighways: true}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "route_modifiers only apply to the DRIVE and TWO_WHEELER travel modes",
+		},
+		{
+			name: "transit preferences without the transit travel mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].TransitPreferences = TransitPreferences{RoutingPreference: "LESS_WALKING"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "transit_preferences only apply to the TRANSIT travel mode",
+		},
+		{
+			name: "invalid transit mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TransitPreferences = TransitPreferences{AllowedModes: []string{"FERRY"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "invalid transit mode FERRY",
+		},
+		{
+			name: "invalid transit routing preference",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TransitPreferences = TransitPreferences{RoutingPreference: "FEWER_BUSES"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "transit routing_preference must be LESS_WALKING or FEWER_TRANSFERS",
+		},
+		{
+			name: "route modifiers with a provider other than google",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].Provider = ProviderOsrm
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].RouteModifiers = RouteModifiers{AvoidTolls: true}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "route_modifiers and transit_preferences are only supported by the google provider",
+		},
+		{
+			name: "address and place id instead of coordinates",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Origin = Location{Name: "10 Downing Street", Address: "10 Downing Street, London SW1A 2AA"}
+				cfg.Rules[0].Destination = Location{Name: "Palace of Westminster", PlaceID: "ChIJJ5kaocQEdkgRKtmiHtu0p1M"}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "location without coordinates, address or place id",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Destination = Location{Name: "Palace of Westminster"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "Palace of Westminster must have coordinates, an address or a place_id",
+		},
+		{
+			name: "location with both coordinates and an address",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Origin.Address = "10 Downing Street, London SW1A 2AA"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "10 Downing Street must have only one of coordinates, an address and a place_id",
+		},
+		{
+			name: "address with a provider other than google",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].Provider = ProviderOsrm
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].Origin = Location{Name: "10 Downing Street", Address: "10 Downing Street, London SW1A 2AA"}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "the osrm provider needs coordinates for 10 Downing Street",
+		},
+		{
+			name: "geocoded address with a provider other than google",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Routing.Google.Geocode = true
+				cfg.Rules[0].Provider = ProviderOsrm
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].Origin = Location{Name: "10 Downing Street", Address: "10 Downing Street, London SW1A 2AA"}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "baseline from history without history path",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelTime = TravelTime{MinutesAboveBaseline: 10, Baseline: Baseline{HistorySamples: 20}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "history path must be specified to use a baseline from history",
+		},
+		{
+			name: "empty times list",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "at least one time must be specified",
+		},
+		{
+			name: "invalid weekday",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times[0].Day = "FUNDAY"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidDay.Error(),
+		},
+		{
+			name: "invalid time format",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times[0].Time = "9 AM"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTimeFormat.Error(),
+		},
+		{
+			name: "valid travel mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelMode = "DRIVE"
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid travel mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelMode = "HOVERCRAFT"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTravelMode.Error(),
+		},
+		{
+			name: "valid departure time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].DepartureTime = "08:30"
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid departure time format",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].DepartureTime = "half eight"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTimeFormat.Error(),
+		},
+		{
+			name: "invalid arrival time format",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].ArrivalTime = "25:00"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTimeFormat.Error(),
+		},
+		{
+			name: "both departure and arrival time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].DepartureTime = "08:30"
+				cfg.Rules[0].ArrivalTime = "09:00"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "only one of departure_time and arrival_time may be specified",
+		},
+		{
+			name: "arrival time with driving",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].TravelMode = "DRIVE"
+				cfg.Rules[0].ArrivalTime = "09:00"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "arrival_time is only supported for the TRANSIT travel mode",
+		},
+		{
+			name: "invalid default provider",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Provider = "mapquest"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidProvider.Error(),
+		},
+		{
+			name: "invalid rule provider",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Provider = "mapquest"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidProvider.Error(),
+		},
+		{
+			name: "opentripplanner provider with url",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.OpenTripPlanner.Url = "http://localhost:8080/otp/gtfs/v1"
+				cfg.Rules[0].Provider = ProviderOpenTripPlanner
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "opentripplanner provider without url",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Provider = ProviderOpenTripPlanner
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "opentripplanner url must be specified",
+		},
+		{
+			name: "osrm provider with url",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Provider = ProviderOsrm
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].TravelMode = "DRIVE"
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "valhalla provider without url",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Provider = ProviderValhalla
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "valhalla url must be specified",
+		},
+		{
+			name: "osrm provider with default transit travel mode",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Routing.Osrm.Url = "http://localhost:5000"
+				cfg.Rules[0].Provider = ProviderOsrm
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "the osrm provider does not support the TRANSIT travel mode",
+		},
+		{
+			name: "valid time window",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Days: []string{"MONDAY", "FRIDAY"}, From: "07:00", To: "08:30", Every: "10m"}}
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "time window with invalid day",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Days: []string{"FUNDAY"}, From: "07:00", To: "08:30", Every: "10m"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidDay.Error(),
+		},
+		{
+			name: "time window without days",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{From: "07:00", To: "08:30", Every: "10m"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a time window must have at least one day",
+		},
+		{
+			name: "time window mixed with day and time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times[0].Every = "10m"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a time window must use days, from, to and every instead of day and time",
+		},
+		{
+			name: "time window ending before it starts",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Days: []string{"MONDAY"}, From: "08:30", To: "07:00", Every: "10m"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a time window must end after it starts",
+		},
+		{
+			name: "time window with invalid interval",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Days: []string{"MONDAY"}, From: "07:00", To: "08:30", Every: "10s"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a time window must repeat every whole number of minutes",
+		},
+		{
+			name: "time window with interval in seconds",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Days: []string{"MONDAY"}, From: "07:00", To: "08:30", Every: "2m30s"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a time window must repeat every whole number of minutes",
+		},
+		{
+			name: "time window with invalid time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Days: []string{"MONDAY"}, From: "7am", To: "08:30", Every: "10m"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  errInvalidTimeFormat.Error(),
+		},
+		{
+			name: "valid cron schedule",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = append(cfg.Rules[0].Times, TimeSchedule{Cron: "*/15 7-9 * * MON-FRI"})
+				return cfg
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "invalid cron schedule",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times = []TimeSchedule{{Cron: "*/15 7-9 * MON-FRI"}}
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "cron expression must have 5 fields",
+		},
+		{
+			name: "cron schedule mixed with day and time",
+			cfg: func() Config {
+				cfg := validConfig()
+				cfg.Rules[0].Times[0].Cron = "0 9 * * *"
+				return cfg
+			}(),
+			wantErr: true,
+			errMsg:  "a cron schedule must not also have a day, time or window",
 		},
 		{
 			name: "invalid timezone",
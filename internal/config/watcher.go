@@ -0,0 +1,40 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// WatchFile polls the file every interval and calls onChange when its modification time or size changes,
+// until the context is cancelled. Polling also works for files mounted into containers, where file system
+// notifications are often not delivered.
+func WatchFile(ctx context.Context, filename string, interval time.Duration, onChange func()) {
+	lastModTime, lastSize := stat(filename)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, size := stat(filename)
+			if modTime.Equal(lastModTime) && size == lastSize {
+				continue
+			}
+			lastModTime, lastSize = modTime, size
+			onChange()
+		}
+	}
+}
+
+func stat(filename string) (time.Time, int64) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		slog.Warn("Failed to check config file for changes", slog.Any("error", err))
+		return time.Time{}, -1
+	}
+	return info.ModTime(), info.Size()
+}
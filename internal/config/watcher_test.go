@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFile_CallsOnChange(t *testing.T) {
+	// given
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte("rules: []"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	go WatchFile(ctx, filename, 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	// when
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-changed:
+		t.Fatal("expected no change before the file is modified")
+	default:
+	}
+	if err := os.WriteFile(filename, []byte("rules: [] # modified"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// then
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("expected onChange to be called after the file is modified")
+	}
+}
+
+func TestWatchFile_StopsWhenCancelled(t *testing.T) {
+	// given
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		WatchFile(ctx, filename, 10*time.Millisecond, func() {})
+		close(done)
+	}()
+
+	// when
+	cancel()
+
+	// then
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected WatchFile to return after the context is cancelled")
+	}
+}
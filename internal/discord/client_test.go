@@ -0,0 +1,58 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+func TestNotify_Success(t *testing.T) {
+	// given
+	var received Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/webhooks/123/abc" {
+			t.Errorf("unexpected URL path %q", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL + "/api/webhooks/123/abc")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	if received.Content != "Hello, world!" {
+		t.Errorf("expected content %q, got %q", "Hello, world!", received.Content)
+	}
+}
+
+func TestNotify_ErrorResponse(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message": "Unknown Webhook", "code": 10015}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	expectedErr := "bad status code received: 404"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
@@ -0,0 +1,189 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+	"wayfarer/internal/notify"
+)
+
+const (
+	SecurityStartTLS = "starttls" // Upgrade a plain connection, usually on port 587
+	SecurityTLS      = "tls"      // Implicit TLS, usually on port 465
+	SecurityNone     = "none"     // Only suitable for local relays
+)
+
+// Client sends emails over SMTP
+type Client struct {
+	Host      string
+	Port      int
+	Security  string
+	Username  string // Optional, no authentication if empty
+	Password  string
+	TLSConfig *tls.Config // Optional, defaults to verifying the certificate of Host
+	Logger    *slog.Logger
+}
+
+func NewClient(host string, port int, security string, username string, password string) *Client {
+	return &Client{
+		Host:     host,
+		Port:     port,
+		Security: security,
+		Username: username,
+		Password: password,
+		Logger:   slog.Default(),
+	}
+}
+
+// SendMail sends a multipart email with plain text and HTML alternatives of the same text
+func (c *Client) SendMail(ctx context.Context, from string, to string, subject string, text string) error {
+	body, err := buildMessage(from, to, subject, text)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	client, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+	// net/smtp has no context support, so abort the conversation by closing the connection
+	stop := context.AfterFunc(ctx, func() { _ = client.Close() })
+	defer stop()
+	defer func(client *smtp.Client) {
+		err := client.Close()
+		if err != nil {
+			c.Logger.Debug("Failed to close SMTP connection", slog.Any("error", err))
+		}
+	}(client)
+
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(body); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := client.Quit(); err != nil {
+		return err
+	}
+
+	c.Logger.Info("Email sent successfully", slog.String("to", to))
+	return nil
+}
+
+func (c *Client) connect(ctx context.Context) (*smtp.Client, error) {
+	address := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	tlsConfig := c.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: c.Host}
+	}
+
+	var conn net.Conn
+	var err error
+	if c.Security == SecurityTLS {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if c.Security == SecurityStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	return client, nil
+}
+
+func buildMessage(from string, to string, subject string, text string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	body.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	htmlText := "<html><body><p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>") + "</p></body></html>"
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlText},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(partWriter, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// Notifier sends notifications by email to a single address
+type Notifier struct {
+	Client *Client
+	From   string
+	To     string
+}
+
+func (n *Notifier) Notify(ctx context.Context, message notify.Message) error {
+	return n.Client.SendMail(ctx, n.From, n.To, message.Title, message.Text)
+}
@@ -0,0 +1,258 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+// fakeSmtpServer is an in-process stand-in for an SMTP server, recording the mail it receives
+type fakeSmtpServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu         sync.Mutex
+	from       string
+	recipients []string
+	data       string
+	auth       string
+	upgraded   bool
+}
+
+func startFakeSmtpServer(t *testing.T, implicitTLS bool) (*fakeSmtpServer, *tls.Config) {
+	// Borrow the self-signed certificate of a TLS test server
+	certServer := httptest.NewTLSServer(nil)
+	t.Cleanup(certServer.Close)
+	serverTLSConfig := &tls.Config{Certificates: certServer.TLS.Certificates}
+	roots := x509.NewCertPool()
+	roots.AddCert(certServer.Certificate())
+	clientTLSConfig := &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"}
+
+	var listener net.Listener
+	var err error
+	if implicitTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", serverTLSConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &fakeSmtpServer{listener: listener, tlsConfig: serverTLSConfig}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn)
+		}
+	}()
+	return server, clientTLSConfig
+}
+
+func (s *fakeSmtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSmtpServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250-STARTTLS")
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			s.mu.Lock()
+			s.upgraded = true
+			s.mu.Unlock()
+		case "AUTH":
+			s.mu.Lock()
+			s.auth = line
+			s.mu.Unlock()
+			reply("235 Authentication successful")
+		case "MAIL":
+			s.mu.Lock()
+			s.from = line
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RCPT":
+			s.mu.Lock()
+			s.recipients = append(s.recipients, line)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSendMail_MultipartMessage(t *testing.T) {
+	// given
+	server, _ := startFakeSmtpServer(t, false)
+	client := NewClient("127.0.0.1", server.port(), SecurityNone, "", "")
+
+	// when
+	err := client.SendMail(context.Background(), "wayfarer@example.com", "commuter@example.com", "Travel time to Palace of Westminster", "Travel time is <long> & slow")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.from != "MAIL FROM:<wayfarer@example.com>" {
+		t.Errorf("unexpected sender: %q", server.from)
+	}
+	if len(server.recipients) != 1 || server.recipients[0] != "RCPT TO:<commuter@example.com>" {
+		t.Errorf("unexpected recipients: %q", server.recipients)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("failed to parse email: %v", err)
+	}
+	if msg.Header.Get("Subject") != "Travel time to Palace of Westminster" {
+		t.Errorf("unexpected subject: %q", msg.Header.Get("Subject"))
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (%v)", mediaType, err)
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		parts[part.Header.Get("Content-Type")] = string(content)
+	}
+	if parts["text/plain; charset=utf-8"] != "Travel time is <long> & slow" {
+		t.Errorf("unexpected plain text part: %q", parts["text/plain; charset=utf-8"])
+	}
+	if !strings.Contains(parts["text/html; charset=utf-8"], "Travel time is &lt;long&gt; &amp; slow") {
+		t.Errorf("unexpected HTML part: %q", parts["text/html; charset=utf-8"])
+	}
+}
+
+func TestSendMail_StartTLSWithAuthentication(t *testing.T) {
+	// given
+	server, tlsConfig := startFakeSmtpServer(t, false)
+	client := NewClient("127.0.0.1", server.port(), SecurityStartTLS, "user", "secret")
+	client.TLSConfig = tlsConfig
+
+	// when
+	err := client.SendMail(context.Background(), "wayfarer@example.com", "commuter@example.com", "Subject", "Hello, world!")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !server.upgraded {
+		t.Error("expected connection to be upgraded with STARTTLS")
+	}
+	expectedAuth := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))
+	if server.auth != expectedAuth {
+		t.Errorf("expected %q, got %q", expectedAuth, server.auth)
+	}
+}
+
+func TestNotifier_ImplicitTLS(t *testing.T) {
+	// given
+	server, tlsConfig := startFakeSmtpServer(t, true)
+	client := NewClient("127.0.0.1", server.port(), SecurityTLS, "", "")
+	client.TLSConfig = tlsConfig
+	notifier := &Notifier{Client: client, From: "wayfarer@example.com", To: "commuter@example.com"}
+
+	// when
+	err := notifier.Notify(context.Background(), notify.Message{Title: "Subject", Text: "Hello, world!"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !strings.Contains(server.data, "Hello, world!") {
+		t.Errorf("expected email to contain the message, got %q", server.data)
+	}
+}
+
+func TestSendMail_ConnectionRefused(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	client := NewClient("127.0.0.1", port, SecurityNone, "", "")
+
+	// when
+	err = client.SendMail(context.Background(), "wayfarer@example.com", "commuter@example.com", "Subject", "Hello, world!")
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to SMTP server") {
+		t.Errorf("expected connection error, got %v", err)
+	}
+}
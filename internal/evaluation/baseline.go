@@ -0,0 +1,110 @@
+package evaluation
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+	"wayfarer/internal/config"
+	"wayfarer/internal/history"
+)
+
+// Measurements within this long of the time of day of an evaluation are in the same time slot
+const timeSlotTolerance = 15 * time.Minute
+
+// A baseline from history needs at least this many measurements, or all of them if fewer are configured
+const minBaselineSamples = 3
+
+// baseline returns the usual duration of the journey, and false if it isn't known yet
+func (e *Evaluator) baseline(now time.Time) (time.Duration, bool) {
+	baseline := e.rule.TravelTime.Baseline
+	if baseline.Minutes > 0 {
+		return time.Duration(baseline.Minutes) * time.Minute, true
+	}
+	if e.measurements == nil {
+		return 0, false
+	}
+
+	// Each previous week has a measurement in the time slot if the rule is evaluated weekly
+	samples := baseline.HistorySamples
+	from := now.AddDate(0, 0, -7*samples).Add(-timeSlotTolerance)
+	measurements, err := e.measurements.Measurements(e.rule.Id, from, now)
+	if err != nil {
+		slog.Error("Failed to read history", slog.Any("error", err), slog.Any("rule_id", e.rule.Id))
+		return 0, false
+	}
+
+	durations := weeklySamples(measurements, now, e.timezone, samples)
+	if len(durations) < min(samples, minBaselineSamples) {
+		return 0, false
+	}
+	return median(durations), true
+}
+
+// weeklySamples returns the durations of up to the given number of previous weeks, most recent first. Each week
+// contributes the measurement closest to the time of day of now, so a rule evaluated every few minutes doesn't make
+// today's journey, e.g. an ongoing delay, its own baseline.
+func weeklySamples(measurements []history.Measurement, now time.Time, timezone *time.Location, samples int) []time.Duration {
+	closest := make(map[string]history.Measurement) // By date
+	var dates []string
+	for _, measurement := range measurements {
+		if sameDay(measurement.Timestamp, now, timezone) || !sameTimeSlot(measurement.Timestamp, now, timezone) {
+			continue
+		}
+		date := measurement.Timestamp.In(timezone).Format(time.DateOnly)
+		previous, ok := closest[date]
+		if !ok {
+			dates = append(dates, date)
+		}
+		if !ok || timeOfDayDistance(measurement.Timestamp, now, timezone) < timeOfDayDistance(previous.Timestamp, now, timezone) {
+			closest[date] = measurement
+		}
+	}
+
+	slices.Sort(dates)
+	slices.Reverse(dates)
+	var durations []time.Duration
+	for _, date := range dates[:min(len(dates), samples)] {
+		durations = append(durations, closest[date].Duration)
+	}
+	return durations
+}
+
+// levelThreshold returns the journey duration above which a journey is delayed at the level
+func levelThreshold(level config.Level, baseline time.Duration) time.Duration {
+	switch {
+	case level.PercentAboveBaseline > 0:
+		return baseline + baseline*time.Duration(level.PercentAboveBaseline)/100
+	case level.MinutesAboveBaseline > 0:
+		return baseline + time.Duration(level.MinutesAboveBaseline)*time.Minute
+	default:
+		return time.Duration(level.NotificationThresholdMinutes) * time.Minute
+	}
+}
+
+// sameTimeSlot checks if both times are on the same weekday and at about the same time of day
+func sameTimeSlot(a, b time.Time, timezone *time.Location) bool {
+	return a.In(timezone).Weekday() == b.In(timezone).Weekday() && timeOfDayDistance(a, b, timezone) <= timeSlotTolerance
+}
+
+// timeOfDayDistance returns how far apart the times of day are, regardless of the date
+func timeOfDayDistance(a, b time.Time, timezone *time.Location) time.Duration {
+	timeOfDay := func(t time.Time) time.Duration {
+		t = t.In(timezone)
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	}
+	difference := timeOfDay(a) - timeOfDay(b)
+	if difference < 0 {
+		return -difference
+	}
+	return difference
+}
+
+func median(durations []time.Duration) time.Duration {
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
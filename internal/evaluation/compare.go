@@ -0,0 +1,45 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+var travelModeNames = map[routing.TravelMode]string{
+	routing.TravelModeTransit:    "transit",
+	routing.TravelModeDrive:      "driving",
+	routing.TravelModeBicycle:    "cycling",
+	routing.TravelModeWalk:       "walking",
+	routing.TravelModeTwoWheeler: "motorcycling",
+}
+
+// compareModes fetches the journey by each travel mode to compare with, and describes them alongside the journey by
+// the travel mode of the rule, e.g. "Transit 58 min (+20), driving 41 min: driving is fastest". The difference is to
+// the reference, i.e. the threshold or usual travel time.
+func (e *Evaluator) compareModes(ctx context.Context, request routing.Request, duration time.Duration, reference time.Duration) string {
+	descriptions := []string{fmt.Sprintf("%s %.0f min (%+.0f)", travelModeNames[e.travelMode], duration.Minutes(), (duration - reference).Minutes())}
+	fastestMode, fastest := e.travelMode, duration
+	for _, mode := range e.compareTravelModes {
+		request.TravelMode = mode
+		journey, err := e.provider.FetchJourney(ctx, request)
+		if err != nil {
+			slog.Warn("Failed to fetch travel time to compare", slog.Any("error", err), slog.Any("rule_id", e.rule.Id),
+				slog.String("travel_mode", string(mode)))
+			continue
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s %.0f min", travelModeNames[mode], journey.Duration.Minutes()))
+		if journey.Duration < fastest {
+			fastestMode, fastest = mode, journey.Duration
+		}
+	}
+
+	comparison := strings.Join(descriptions, ", ")
+	if fastestMode != e.travelMode {
+		comparison += fmt.Sprintf(": %s is fastest", travelModeNames[fastestMode])
+	}
+	return strings.ToUpper(comparison[:1]) + comparison[1:]
+}
@@ -0,0 +1,486 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"wayfarer/internal/config"
+	"wayfarer/internal/history"
+	"wayfarer/internal/notify"
+	"wayfarer/internal/routing"
+	"wayfarer/internal/scheduling"
+)
+
+// Status of a rule's journey, notifications are only sent when it changes. A rule with several levels is delayed
+// at the level numbered from 1, e.g. Status(2) for the second level.
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusDelayed
+)
+
+func (s Status) String() string {
+	if s == StatusDelayed {
+		return "DELAYED"
+	}
+	return "OK"
+}
+
+// maxAlternatives is how many alternative routes are listed in a delay notification
+const maxAlternatives = 2
+
+// MeasurementStore stores the result of each evaluation and reads them back for baselines, e.g. a history.Store
+type MeasurementStore interface {
+	Record(measurement history.Measurement) error
+	Measurements(ruleID int, from time.Time, to time.Time) ([]history.Measurement, error)
+}
+
+// Evaluator checks the journey of a single rule and notifies its user when the status changes
+type Evaluator struct {
+	rule               config.Rule
+	provider           routing.Provider
+	notifier           notify.Notifier
+	levels             []level
+	measurements       MeasurementStore // Optional
+	timezone           *time.Location
+	travelMode         routing.TravelMode
+	compareTravelModes []routing.TravelMode
+	legs               []leg // Only for rules with waypoints
+	departureTime      time.Time
+	arrivalTime        time.Time
+
+	mu            sync.Mutex
+	status        Status
+	lastEvaluated time.Time
+	failing       bool  // Whether the last journey couldn't be fetched, so a run of failures is only notified once
+	alerted       []int // Indexes of the levels notified since the status was last OK
+
+	now func() time.Time // Can be overridden in tests
+}
+
+// level is a severity level of the rule, with the notifier for its channels
+type level struct {
+	config.Level
+	notifier    notify.Notifier
+	ownChannels bool               // Whether the notifier is the level's own rather than the user's
+	message     *template.Template // Optional
+}
+
+// messageData is available to the message templates of levels
+type messageData struct {
+	Label            string
+	Origin           string
+	Destination      string
+	Minutes          int // Current travel time
+	ThresholdMinutes int
+	MinutesVsUsual   int    // Difference to the baseline, if the threshold is relative
+	Comparison       string // Travel times by the travel modes to compare with, if any
+	Legs             string // Travel time of each leg, if the rule has waypoints
+	Summary          string // Transit lines and changes, if known, e.g. "Northern line 08:12 → Bank, 2 changes"
+	Alternatives     string // Travel times of the next fastest routes, if requested, e.g. "Other routes: 45 min via A40"
+}
+
+// NewEvaluator expects a rule already validated by config.LoadConfig, with its provider resolved so it can be
+// recorded. The level notifiers are indexed like the levels of the rule, nil or missing entries use the user's
+// notifier instead. The measurement store may be nil.
+func NewEvaluator(rule config.Rule, provider routing.Provider, notifier notify.Notifier, levelNotifiers []notify.Notifier, measurements MeasurementStore) *Evaluator {
+	var levels []level
+	for i, configLevel := range rule.TravelTime.AllLevels() {
+		level := level{Level: configLevel, notifier: notifier}
+		if i < len(levelNotifiers) && levelNotifiers[i] != nil {
+			level.notifier = levelNotifiers[i]
+			level.ownChannels = true
+		}
+		if configLevel.Message != "" {
+			level.message, _ = template.New("message").Parse(configLevel.Message)
+		}
+		levels = append(levels, level)
+	}
+	timezone, _ := time.LoadLocation(rule.Timezone)
+	travelMode, _ := routing.ParseTravelMode(rule.TravelMode)
+	var compareTravelModes []routing.TravelMode
+	for _, mode := range rule.CompareModes {
+		compareTravelMode, _ := routing.ParseTravelMode(mode)
+		compareTravelModes = append(compareTravelModes, compareTravelMode)
+	}
+	departureTime, _ := time.Parse("15:04", rule.DepartureTime)
+	arrivalTime, _ := time.Parse("15:04", rule.ArrivalTime)
+
+	return &Evaluator{
+		rule:               rule,
+		provider:           provider,
+		notifier:           notifier,
+		levels:             levels,
+		measurements:       measurements,
+		timezone:           timezone,
+		travelMode:         travelMode,
+		compareTravelModes: compareTravelModes,
+		legs:               newLegs(rule, travelMode),
+		departureTime:      departureTime,
+		arrivalTime:        arrivalTime,
+		status:             StatusOK,
+		now:                time.Now,
+	}
+}
+
+// Evaluate fetches the current journey and sends a notification if it has become delayed or back to normal
+func (e *Evaluator) Evaluate(ctx context.Context) {
+	rule := e.rule
+	now := e.now()
+
+	request := e.newRequest(rule.Origin, rule.Destination, e.travelMode)
+	request.Alternatives = rule.AlternativeRoutes
+
+	// Resolve the departure or arrival time to its next occurrence, if any. It is left unset, i.e. now, during its minute.
+	journeyDescription := ""
+	if rule.DepartureTime != "" {
+		request.DepartureTime = scheduling.NextTimeOfDay(now, e.departureTime.Hour(), e.departureTime.Minute(), e.timezone)
+		journeyDescription = fmt.Sprintf(" for the %s departure", rule.DepartureTime)
+	}
+	if rule.ArrivalTime != "" {
+		request.ArrivalTime = scheduling.NextTimeOfDay(now, e.arrivalTime.Hour(), e.arrivalTime.Minute(), e.timezone)
+		journeyDescription = fmt.Sprintf(" to arrive by %s", rule.ArrivalTime)
+	}
+
+	var journey *routing.Journey
+	var breakdown string
+	var err error
+	if len(e.legs) > 0 {
+		journey, breakdown, err = e.fetchLegs(ctx, request, now)
+	} else {
+		journey, err = e.provider.FetchJourney(ctx, request)
+	}
+	if err != nil {
+		slog.Error("Failed to fetch transit time", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+		e.notifyFailure(ctx, err)
+		return
+	}
+	e.setFailing(false)
+	routeDuration := journey.Duration
+
+	baseline, hasBaseline := time.Duration(0), false
+	if rule.TravelTime.IsRelative() {
+		baseline, hasBaseline = e.baseline(now)
+		if !hasBaseline {
+			// Keep the status until there is enough history to tell what is usual
+			slog.Info("Not enough history for a baseline yet", slog.Any("rule_id", rule.Id), slog.Any("duration", routeDuration))
+			e.record(now, routeDuration, 0, false)
+			return
+		}
+	}
+
+	// Pick the highest level exceeded
+	thresholds := make([]time.Duration, len(e.levels))
+	status := StatusOK
+	for i, level := range e.levels {
+		thresholds[i] = levelThreshold(level.Level, baseline)
+		if routeDuration > thresholds[i] {
+			status = Status(i + 1)
+		}
+	}
+	previous, changed := e.transition(now, status)
+	slog.Info("Evaluated travel time", slog.Any("rule_id", rule.Id), slog.Any("duration", routeDuration),
+		slog.String("status", status.String()), slog.String("previous_status", previous.String()), slog.Int("level", int(status)))
+	if !changed {
+		e.record(now, routeDuration, thresholds[0], false)
+		return
+	}
+
+	var message string
+	var notifier notify.Notifier
+	if status > StatusOK {
+		level := e.levels[status-1]
+		notifier = level.notifier
+		comparison := ""
+		if len(e.compareTravelModes) > 0 {
+			reference := thresholds[status-1]
+			if hasBaseline {
+				reference = baseline
+			}
+			comparison = e.compareModes(ctx, request, routeDuration, reference)
+		}
+		message = e.delayedMessage(level, journeyDescription, journey, breakdown, thresholds[status-1], baseline, hasBaseline, comparison)
+	} else {
+		// Tell whoever was told about the delay
+		notifier = e.alertedNotifiers(previous)
+		message = fmt.Sprintf("Travel time between %s and %s%s is back to normal: currently scheduled to take %.0f minutes",
+			rule.Origin.Name, rule.Destination.Name, journeyDescription, routeDuration.Minutes())
+		if hasBaseline {
+			message += fmt.Sprintf(" (%+.0f min vs usual)", (routeDuration - baseline).Minutes())
+		}
+	}
+	title := fmt.Sprintf("Travel time to %s", rule.Destination.Name)
+	priority := notify.PriorityFor(routeDuration, thresholds[0])
+	err = notifier.Notify(ctx, notify.Message{Title: title, Text: message, Priority: priority})
+	// Reaching some of the channels counts, so those channels aren't sent the message again
+	delivered := err == nil || errors.Is(err, notify.ErrPartiallyDelivered)
+	switch {
+	case delivered:
+		if err != nil {
+			slog.Error("Failed to send message", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+		}
+		e.alert(status)
+	case errors.Is(err, notify.ErrUnreachable):
+		// Retrying won't reach the user either, so the status moves on without them being told
+		slog.Warn("User is unreachable, not notified", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+		if status == StatusOK {
+			e.alert(status)
+		}
+	default:
+		slog.Error("Failed to send message", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+		// The change wasn't reported, so the next evaluation tries again
+		e.revert(previous)
+	}
+	e.record(now, routeDuration, thresholds[0], delivered)
+}
+
+// delayedMessage uses the message template of the level if it has one
+func (e *Evaluator) delayedMessage(level level, journeyDescription string, journey *routing.Journey, breakdown string, threshold time.Duration, baseline time.Duration, hasBaseline bool, comparison string) string {
+	rule := e.rule
+	duration := journey.Duration
+	summary := journey.Summary(e.timezone)
+	alternatives := describeAlternatives(journey, e.timezone)
+	var message string
+	if hasBaseline {
+		message = fmt.Sprintf("Travel time between %s and %s%s is slower than usual: currently scheduled to take %.0f minutes (%+.0f min vs usual)",
+			rule.Origin.Name, rule.Destination.Name, journeyDescription, duration.Minutes(), (duration - baseline).Minutes())
+	} else {
+		message = fmt.Sprintf("Travel time between %s and %s%s is greater than %d minutes: currently scheduled to take %.0f minutes",
+			rule.Origin.Name, rule.Destination.Name, journeyDescription, level.NotificationThresholdMinutes, duration.Minutes())
+	}
+	if level.Label != "" {
+		message = fmt.Sprintf("%s: %s", level.Label, message)
+	}
+	if breakdown != "" {
+		message = fmt.Sprintf("%s. %s", message, breakdown)
+	}
+	if summary != "" {
+		message = fmt.Sprintf("%s. %s", message, summary)
+	}
+	if comparison != "" {
+		message = fmt.Sprintf("%s. %s", message, comparison)
+	}
+	if alternatives != "" {
+		message = fmt.Sprintf("%s. %s", message, alternatives)
+	}
+	if level.message == nil {
+		return message
+	}
+
+	data := messageData{
+		Label:            level.Label,
+		Origin:           rule.Origin.Name,
+		Destination:      rule.Destination.Name,
+		Minutes:          int(duration.Round(time.Minute).Minutes()),
+		ThresholdMinutes: int(threshold.Round(time.Minute).Minutes()),
+		Comparison:       comparison,
+		Legs:             breakdown,
+		Summary:          summary,
+		Alternatives:     alternatives,
+	}
+	if hasBaseline {
+		data.MinutesVsUsual = int((duration - baseline).Round(time.Minute).Minutes())
+	}
+	var text strings.Builder
+	if err := level.message.Execute(&text, data); err != nil {
+		slog.Error("Failed to render message template, using the default message", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+		return message
+	}
+	return text.String()
+}
+
+// newRequest builds the request for the journey of the rule, or one of its legs
+func (e *Evaluator) newRequest(origin config.Location, destination config.Location, travelMode routing.TravelMode) routing.Request {
+	modifiers := e.rule.RouteModifiers
+	preferences := e.rule.TransitPreferences
+	return routing.Request{
+		Origin:      toLocation(origin),
+		Destination: toLocation(destination),
+		TravelMode:  travelMode,
+		RouteModifiers: routing.RouteModifiers{
+			AvoidTolls:    modifiers.AvoidTolls,
+			AvoidHighways: modifiers.AvoidHighways,
+			AvoidFerries:  modifiers.AvoidFerries,
+		},
+		TransitPreferences: routing.TransitPreferences{
+			AllowedModes:      preferences.AllowedModes,
+			RoutingPreference: preferences.RoutingPreference,
+		},
+	}
+}
+
+// toLocation passes on the address or place ID of a location only if it hasn't been geocoded to coordinates
+func toLocation(location config.Location) routing.Location {
+	if location.HasCoordinates() {
+		return routing.Location{Latitude: location.Latitude, Longitude: location.Longitude}
+	}
+	return routing.Location{Address: location.Address, PlaceID: location.PlaceID}
+}
+
+// describeAlternatives lists the next fastest routes after the journey, by their description or else their summary
+func describeAlternatives(journey *routing.Journey, timezone *time.Location) string {
+	var routes []string
+	for _, alternative := range journey.Alternatives[:min(len(journey.Alternatives), maxAlternatives)] {
+		route := fmt.Sprintf("%.0f min", alternative.Duration.Minutes())
+		if alternative.Description != "" {
+			route += " via " + alternative.Description
+		} else if summary := alternative.Summary(timezone); summary != "" {
+			route += fmt.Sprintf(" (%s)", summary)
+		}
+		routes = append(routes, route)
+	}
+	if len(routes) == 0 {
+		return ""
+	}
+	return "Other routes: " + strings.Join(routes, ", ")
+}
+
+func (e *Evaluator) record(now time.Time, duration time.Duration, threshold time.Duration, notified bool) {
+	if e.measurements == nil {
+		return
+	}
+	err := e.measurements.Record(history.Measurement{
+		RuleID:     e.rule.Id,
+		Timestamp:  now,
+		Provider:   e.rule.Provider,
+		TravelMode: string(e.travelMode),
+		Duration:   duration,
+		Threshold:  threshold,
+		Notified:   notified,
+	})
+	if err != nil {
+		slog.Error("Failed to record measurement", slog.Any("error", err), slog.Any("rule_id", e.rule.Id))
+	}
+}
+
+// notifyFailure tells the user the journey couldn't be checked, unless they were already told since the last
+// successful check or the evaluation was cancelled, e.g. on shutdown
+func (e *Evaluator) notifyFailure(ctx context.Context, err error) {
+	if ctx.Err() != nil || e.setFailing(true) {
+		return
+	}
+	reason := "the route couldn't be computed"
+	if errors.Is(err, routing.ErrUnavailable) {
+		reason = "routing service unavailable"
+	}
+	rule := e.rule
+	message := fmt.Sprintf("Couldn't check your commute between %s and %s: %s", rule.Origin.Name, rule.Destination.Name, reason)
+	title := fmt.Sprintf("Travel time to %s", rule.Destination.Name)
+	err = e.notifier.Notify(ctx, notify.Message{Title: title, Text: message, Priority: notify.PriorityLow})
+	if err != nil {
+		slog.Error("Failed to send message", slog.Any("error", err), slog.Any("rule_id", rule.Id))
+	}
+}
+
+// setFailing records whether the journey could be fetched, returning whether the previous fetch failed
+func (e *Evaluator) setFailing(failing bool) (wasFailing bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	wasFailing = e.failing
+	e.failing = failing
+	return wasFailing
+}
+
+// State is the status of an evaluator, which can be carried over to the evaluator of a rescheduled rule
+type State struct {
+	status        Status
+	lastEvaluated time.Time
+	failing       bool
+	alerted       []int
+}
+
+// State returns the status of the rule, e.g. to keep it when the rule is rescheduled after a config reload
+func (e *Evaluator) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return State{status: e.status, lastEvaluated: e.lastEvaluated, failing: e.failing, alerted: slices.Clone(e.alerted)}
+}
+
+// Restore continues from the state of a previous evaluator of the rule, which must have had the same levels
+func (e *Evaluator) Restore(state State) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.status = state.status
+	e.lastEvaluated = state.lastEvaluated
+	e.failing = state.failing
+	e.alerted = slices.Clone(state.alerted)
+}
+
+// transition records the new status, returning the previous one and whether it changed. Only rising to a higher
+// level or returning to OK is a change, so a journey easing from one level to a lower one isn't notified again.
+// The status starts each day as OK, so the first delay of the day is always notified.
+func (e *Evaluator) transition(now time.Time, status Status) (previous Status, changed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous = e.status
+	if !sameDay(e.lastEvaluated, now, e.timezone) {
+		previous = StatusOK
+		e.alerted = nil
+	}
+	e.lastEvaluated = now
+	if status > previous || (status == StatusOK && previous != StatusOK) {
+		e.status = status
+		return previous, true
+	}
+	e.status = previous
+	return previous, false
+}
+
+// alert records that the status was notified, forgetting the levels notified once back to normal
+func (e *Evaluator) alert(status Status) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if status == StatusOK {
+		e.alerted = nil
+	} else if !slices.Contains(e.alerted, int(status)-1) {
+		e.alerted = append(e.alerted, int(status)-1)
+	}
+}
+
+// alertedNotifiers returns the notifiers of the levels notified during the delay, with the user's only once, or the
+// notifier of the previous status if none were notified
+func (e *Evaluator) alertedNotifiers(previous Status) notify.Notifier {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.alerted) == 0 {
+		return e.levels[previous-1].notifier
+	}
+	var notifiers notify.Notifiers
+	userNotified := false
+	for _, i := range e.alerted {
+		level := e.levels[i]
+		if !level.ownChannels {
+			if userNotified {
+				continue
+			}
+			userNotified = true
+		}
+		notifiers = append(notifiers, level.notifier)
+	}
+	return notifiers
+}
+
+// revert restores the status from before a transition
+func (e *Evaluator) revert(previous Status) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.status = previous
+}
+
+func sameDay(a, b time.Time, timezone *time.Location) bool {
+	return a.In(timezone).Format(time.DateOnly) == b.In(timezone).Format(time.DateOnly)
+}
@@ -0,0 +1,682 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"wayfarer/internal/config"
+	"wayfarer/internal/history"
+	"wayfarer/internal/notify"
+	"wayfarer/internal/routing"
+)
+
+type fakeProvider struct {
+	durations     []time.Duration
+	modeDurations map[routing.TravelMode]time.Duration // Used instead of durations for these travel modes
+	transitLegs   []routing.TransitLeg
+	alternatives  []routing.Journey
+	err           error
+	requests      []routing.Request
+}
+
+func (f *fakeProvider) FetchJourney(ctx context.Context, req routing.Request) (*routing.Journey, error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if duration, ok := f.modeDurations[req.TravelMode]; ok {
+		return &routing.Journey{Duration: duration}, nil
+	}
+	duration := f.durations[0]
+	f.durations = f.durations[1:]
+	return &routing.Journey{Duration: duration, TransitLegs: f.transitLegs, Alternatives: f.alternatives}, nil
+}
+
+func (f *fakeProvider) Close() error {
+	return nil
+}
+
+type fakeNotifier struct {
+	messages []notify.Message
+	err      error // Optional, returned instead of sending the message
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, message notify.Message) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+type fakeMeasurementStore struct {
+	measurements []history.Measurement
+}
+
+func (f *fakeMeasurementStore) Record(measurement history.Measurement) error {
+	f.measurements = append(f.measurements, measurement)
+	return nil
+}
+
+func (f *fakeMeasurementStore) Measurements(ruleID int, from time.Time, to time.Time) ([]history.Measurement, error) {
+	var measurements []history.Measurement
+	for _, measurement := range f.measurements {
+		if measurement.RuleID == ruleID && !measurement.Timestamp.Before(from) && measurement.Timestamp.Before(to) {
+			measurements = append(measurements, measurement)
+		}
+	}
+	return measurements, nil
+}
+
+func testRule() config.Rule {
+	return config.Rule{
+		Id:          1,
+		Origin:      config.Location{Name: "10 Downing Street", Longitude: -0.1276, Latitude: 51.503},
+		Destination: config.Location{Name: "Palace of Westminster", Longitude: -0.1246, Latitude: 51.498},
+		User:        config.User{TelegramUserID: 123456789},
+		TravelTime:  config.TravelTime{NotificationThresholdMinutes: 30},
+		Times:       []config.TimeSchedule{{Day: "MONDAY", Time: "07:00"}},
+		Timezone:    "UTC",
+	}
+}
+
+// evaluateAt runs the evaluator once for each time, returning the notifications sent
+func evaluateAt(evaluator *Evaluator, notifier *fakeNotifier, times ...time.Time) []string {
+	for _, now := range times {
+		evaluator.now = func() time.Time { return now }
+		evaluator.Evaluate(context.Background())
+	}
+	texts := make([]string, 0, len(notifier.messages))
+	for _, message := range notifier.messages {
+		texts = append(texts, message.Text)
+	}
+	return texts
+}
+
+func TestEvaluate_NotifiesOnlyOnStatusChanges(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{
+		25 * time.Minute, // OK, no notification
+		40 * time.Minute, // Delayed
+		45 * time.Minute, // Still delayed, no notification
+		31 * time.Minute, // Still delayed
+		29 * time.Minute, // Back to normal
+		20 * time.Minute, // Still normal, no notification
+	}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	var times []time.Time
+	for i := 0; i < 6; i++ {
+		times = append(times, morning.Add(time.Duration(i)*10*time.Minute))
+	}
+	actual := evaluateAt(evaluator, notifier, times...)
+
+	// Then
+	expected := []string{
+		"Travel time between 10 Downing Street and Palace of Westminster is greater than 30 minutes: currently scheduled to take 40 minutes",
+		"Travel time between 10 Downing Street and Palace of Westminster is back to normal: currently scheduled to take 29 minutes",
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %d notifications, got %d: %q", len(expected), len(actual), actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected notification %d to be %q, got %q", i, expected[i], actual[i])
+		}
+	}
+}
+
+func TestEvaluate_StatusResetsEachDay(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{40 * time.Minute, 40 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+	monday := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	tuesday := monday.Add(24 * time.Hour)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, monday, tuesday)
+
+	// Then
+	if len(actual) != 2 {
+		t.Fatalf("Expected a delay notification on each day, got %q", actual)
+	}
+}
+
+func TestEvaluate_RetriesFailedNotification(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{40 * time.Minute, 42 * time.Minute}}
+	notifier := &fakeNotifier{err: errors.New("connection refused")}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	evaluateAt(evaluator, notifier, morning)
+
+	// When
+	notifier.err = nil
+	actual := evaluateAt(evaluator, notifier, morning.Add(10*time.Minute))
+
+	// Then
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is greater than 30 minutes: currently scheduled to take 42 minutes"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+	if evaluator.status != StatusDelayed {
+		t.Errorf("Expected status %s, got %s", StatusDelayed, evaluator.status)
+	}
+}
+
+func TestEvaluate_PartialDeliveryIsNotRetried(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{40 * time.Minute, 41 * time.Minute, 42 * time.Minute}}
+	working := &fakeNotifier{}
+	failing := &fakeNotifier{err: errors.New("webhook gone")}
+	evaluator := NewEvaluator(testRule(), provider, notify.Notifiers{working, failing}, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	actual := evaluateAt(evaluator, working, morning, morning.Add(10*time.Minute), morning.Add(20*time.Minute))
+
+	// Then
+	if len(actual) != 1 {
+		t.Errorf("Expected a single delay notification to the working channel, got %q", actual)
+	}
+	if evaluator.status != StatusDelayed {
+		t.Errorf("Expected status %s, got %s", StatusDelayed, evaluator.status)
+	}
+}
+
+func TestEvaluate_UnreachableUserIsNotRetried(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.Provider = config.ProviderGoogle
+	provider := &fakeProvider{durations: []time.Duration{40 * time.Minute, 42 * time.Minute}}
+	notifier := &fakeNotifier{err: fmt.Errorf("bot blocked: %w", notify.ErrUnreachable)}
+	store := &fakeMeasurementStore{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, store)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	evaluateAt(evaluator, notifier, morning)
+
+	// When
+	notifier.err = nil
+	actual := evaluateAt(evaluator, notifier, morning.Add(10*time.Minute))
+
+	// Then
+	if len(actual) != 0 {
+		t.Errorf("Expected the delay not to be sent again, got %q", actual)
+	}
+	if evaluator.status != StatusDelayed {
+		t.Errorf("Expected status %s, got %s", StatusDelayed, evaluator.status)
+	}
+	if store.measurements[0].Notified {
+		t.Errorf("Expected the undelivered notification not to be recorded as sent, got %+v", store.measurements[0])
+	}
+}
+
+func TestEvaluate_PriorityReflectsOverrun(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{50 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+
+	// When
+	evaluateAt(evaluator, notifier, time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC))
+
+	// Then
+	if len(notifier.messages) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(notifier.messages))
+	}
+	message := notifier.messages[0]
+	if message.Priority != notify.PriorityUrgent {
+		t.Errorf("Expected urgent priority, got %d", message.Priority)
+	}
+	if message.Title != "Travel time to Palace of Westminster" {
+		t.Errorf("Unexpected title %q", message.Title)
+	}
+}
+
+func TestEvaluate_DepartureTime(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.DepartureTime = "08:30"
+	provider := &fakeProvider{durations: []time.Duration{40 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC))
+
+	// Then
+	expectedDeparture := time.Date(2025, 2, 10, 8, 30, 0, 0, time.UTC)
+	if !provider.requests[0].DepartureTime.Equal(expectedDeparture) {
+		t.Errorf("Expected departure at %v, got %v", expectedDeparture, provider.requests[0].DepartureTime)
+	}
+	expected := "Travel time between 10 Downing Street and Palace of Westminster for the 08:30 departure is greater than 30 minutes: currently scheduled to take 40 minutes"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEvaluate_ProviderErrorKeepsStatus(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{40 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	evaluateAt(evaluator, notifier, morning)
+
+	// When
+	provider.err = errors.New("routing service unavailable")
+	evaluateAt(evaluator, notifier, morning.Add(10*time.Minute))
+
+	// Then
+	if len(notifier.messages) != 2 {
+		t.Errorf("Expected the delay and failure notifications, got %d", len(notifier.messages))
+	}
+	if evaluator.status != StatusDelayed {
+		t.Errorf("Expected status to remain %s, got %s", StatusDelayed, evaluator.status)
+	}
+}
+
+func TestEvaluate_NotifiesFailuresOncePerStreak(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{25 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	provider.err = fmt.Errorf("API request to compute routes failed: %w", routing.ErrUnavailable)
+	evaluateAt(evaluator, notifier, morning, morning.Add(10*time.Minute))
+	provider.err = nil
+	evaluateAt(evaluator, notifier, morning.Add(20*time.Minute))
+	provider.err = errors.New("no routes found")
+	messages := evaluateAt(evaluator, notifier, morning.Add(30*time.Minute))
+
+	// Then
+	expected := []string{
+		"Couldn't check your commute between 10 Downing Street and Palace of Westminster: routing service unavailable",
+		"Couldn't check your commute between 10 Downing Street and Palace of Westminster: the route couldn't be computed",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("Expected %q, got %q", expected, messages)
+	}
+	if notifier.messages[0].Title != "Travel time to Palace of Westminster" {
+		t.Errorf("Expected title %q, got %q", "Travel time to Palace of Westminster", notifier.messages[0].Title)
+	}
+}
+
+func TestEvaluate_RecordsMeasurements(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.Provider = config.ProviderGoogle
+	provider := &fakeProvider{durations: []time.Duration{25 * time.Minute, 40 * time.Minute}}
+	notifier := &fakeNotifier{}
+	store := &fakeMeasurementStore{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, store)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	evaluateAt(evaluator, notifier, morning, morning.Add(10*time.Minute))
+
+	// Then
+	expected := []history.Measurement{
+		{RuleID: 1, Timestamp: morning, Provider: "google", TravelMode: "TRANSIT", Duration: 25 * time.Minute, Threshold: 30 * time.Minute, Notified: false},
+		{RuleID: 1, Timestamp: morning.Add(10 * time.Minute), Provider: "google", TravelMode: "TRANSIT", Duration: 40 * time.Minute, Threshold: 30 * time.Minute, Notified: true},
+	}
+	if len(store.measurements) != len(expected) {
+		t.Fatalf("Expected %d measurements, got %+v", len(expected), store.measurements)
+	}
+	for i := range expected {
+		if store.measurements[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], store.measurements[i])
+		}
+	}
+}
+
+func TestEvaluate_PercentAboveFixedBaseline(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime = config.TravelTime{PercentAboveBaseline: 25, Baseline: config.Baseline{Minutes: 30}}
+	provider := &fakeProvider{durations: []time.Duration{
+		37 * time.Minute, // Within 25% of 30 minutes
+		44 * time.Minute, // Delayed
+		32 * time.Minute, // Back to normal
+	}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, morning, morning.Add(10*time.Minute), morning.Add(20*time.Minute))
+
+	// Then
+	expected := []string{
+		"Travel time between 10 Downing Street and Palace of Westminster is slower than usual: currently scheduled to take 44 minutes (+14 min vs usual)",
+		"Travel time between 10 Downing Street and Palace of Westminster is back to normal: currently scheduled to take 32 minutes (+2 min vs usual)",
+	}
+	if len(actual) != len(expected) || actual[0] != expected[0] || actual[1] != expected[1] {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEvaluate_MinutesAboveBaselineFromHistory(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime = config.TravelTime{MinutesAboveBaseline: 10, Baseline: config.Baseline{HistorySamples: 3}}
+	monday := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	store := &fakeMeasurementStore{measurements: []history.Measurement{
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -21), Duration: 20 * time.Minute},
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -14), Duration: 30 * time.Minute},
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -7).Add(10 * time.Minute), Duration: 26 * time.Minute},
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -6), Duration: 60 * time.Minute},         // Different weekday
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -7).Add(time.Hour), Duration: time.Hour}, // Different time slot
+		{RuleID: 2, Timestamp: monday.AddDate(0, 0, -7), Duration: time.Hour},                // Different rule
+	}}
+	provider := &fakeProvider{durations: []time.Duration{37 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, store)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, monday)
+
+	// Then
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is slower than usual: currently scheduled to take 37 minutes (+11 min vs usual)"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+	last := store.measurements[len(store.measurements)-1]
+	if last.Threshold != 36*time.Minute {
+		t.Errorf("Expected threshold of 36 minutes to be recorded, got %v", last.Threshold)
+	}
+}
+
+func TestEvaluate_BaselineExcludesToday(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime = config.TravelTime{MinutesAboveBaseline: 10, Baseline: config.Baseline{HistorySamples: 3}}
+	monday := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	store := &fakeMeasurementStore{measurements: []history.Measurement{
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -21), Duration: 20 * time.Minute},
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -14).Add(-10 * time.Minute), Duration: 40 * time.Minute},
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -14), Duration: 30 * time.Minute}, // Closest of the week
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -14).Add(5 * time.Minute), Duration: 40 * time.Minute},
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -7), Duration: 26 * time.Minute},
+		// An ongoing delay, checked every 5 minutes today
+		{RuleID: 1, Timestamp: monday.Add(-10 * time.Minute), Duration: 45 * time.Minute},
+		{RuleID: 1, Timestamp: monday.Add(-5 * time.Minute), Duration: 45 * time.Minute},
+	}}
+	provider := &fakeProvider{durations: []time.Duration{45 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, store)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, monday)
+
+	// Then
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is slower than usual: currently scheduled to take 45 minutes (+19 min vs usual)"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEvaluate_NotEnoughHistoryForBaseline(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime = config.TravelTime{MinutesAboveBaseline: 10, Baseline: config.Baseline{HistorySamples: 20}}
+	monday := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	store := &fakeMeasurementStore{measurements: []history.Measurement{
+		{RuleID: 1, Timestamp: monday.AddDate(0, 0, -7), Duration: 20 * time.Minute},
+	}}
+	provider := &fakeProvider{durations: []time.Duration{60 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, store)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, monday)
+
+	// Then
+	if len(actual) != 0 {
+		t.Errorf("Expected no notification without a baseline, got %q", actual)
+	}
+	if len(store.measurements) != 2 {
+		t.Errorf("Expected the measurement to be recorded, got %+v", store.measurements)
+	}
+}
+
+func TestEvaluate_Levels(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime = config.TravelTime{
+		Baseline: config.Baseline{Minutes: 30},
+		Levels: []config.Level{
+			{Label: "Heads up", MinutesAboveBaseline: 10},
+			{Label: "Work from home", MinutesAboveBaseline: 25, Message: "{{.Label}}: {{.Origin}} to {{.Destination}} takes {{.Minutes}} minutes ({{.MinutesVsUsual}} more than usual)"},
+		},
+	}
+	provider := &fakeProvider{durations: []time.Duration{
+		45 * time.Minute, // Heads up
+		60 * time.Minute, // Work from home, to its own channel
+		50 * time.Minute, // Easing to heads up, no notification
+		65 * time.Minute, // Still work from home, no notification
+		35 * time.Minute, // Back to normal, to every channel told about the delay
+	}}
+	notifier := &fakeNotifier{}
+	workFromHomeNotifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, []notify.Notifier{nil, workFromHomeNotifier}, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	for i := range 5 {
+		evaluator.now = func() time.Time { return morning.Add(time.Duration(i) * 10 * time.Minute) }
+		evaluator.Evaluate(context.Background())
+	}
+
+	// Then
+	backToNormal := "Travel time between 10 Downing Street and Palace of Westminster is back to normal: currently scheduled to take 35 minutes (+5 min vs usual)"
+	expected := []string{
+		"Heads up: Travel time between 10 Downing Street and Palace of Westminster is slower than usual: currently scheduled to take 45 minutes (+15 min vs usual)",
+		backToNormal,
+	}
+	if len(notifier.messages) != 2 || notifier.messages[0].Text != expected[0] || notifier.messages[1].Text != expected[1] {
+		t.Errorf("Expected %q, got %+v", expected, notifier.messages)
+	}
+	expectedWorkFromHome := []string{
+		"Work from home: 10 Downing Street to Palace of Westminster takes 60 minutes (30 more than usual)",
+		backToNormal,
+	}
+	if len(workFromHomeNotifier.messages) != 2 || workFromHomeNotifier.messages[0].Text != expectedWorkFromHome[0] ||
+		workFromHomeNotifier.messages[1].Text != expectedWorkFromHome[1] {
+		t.Errorf("Expected %q, got %+v", expectedWorkFromHome, workFromHomeNotifier.messages)
+	}
+}
+
+func TestEvaluate_LevelsSharingChannelsRecoverOnce(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime = config.TravelTime{
+		Baseline: config.Baseline{Minutes: 30},
+		Levels:   []config.Level{{MinutesAboveBaseline: 10}, {MinutesAboveBaseline: 25}},
+	}
+	provider := &fakeProvider{durations: []time.Duration{45 * time.Minute, 60 * time.Minute, 35 * time.Minute}}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, morning, morning.Add(10*time.Minute), morning.Add(20*time.Minute))
+
+	// Then
+	if len(actual) != 3 || !strings.Contains(actual[2], "back to normal") {
+		t.Errorf("Expected both levels and a single recovery, got %q", actual)
+	}
+}
+
+func TestEvaluate_CompareModes(t *testing.T) {
+	// Given
+	rule := testRule()
+	rule.TravelTime.NotificationThresholdMinutes = 38
+	rule.CompareModes = []string{"DRIVE", "BICYCLE"}
+	provider := &fakeProvider{
+		durations:     []time.Duration{30 * time.Minute, 58 * time.Minute},
+		modeDurations: map[routing.TravelMode]time.Duration{routing.TravelModeDrive: 41 * time.Minute, routing.TravelModeBicycle: 50 * time.Minute},
+	}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+	morning := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	evaluateAt(evaluator, notifier, morning)
+	requestsWhenOK := len(provider.requests)
+	actual := evaluateAt(evaluator, notifier, morning.Add(10*time.Minute))
+
+	// Then
+	if requestsWhenOK != 1 {
+		t.Errorf("Expected other travel modes to be fetched only for notifications, got %d requests", requestsWhenOK)
+	}
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is greater than 38 minutes: currently scheduled to take 58 minutes. " +
+		"Transit 58 min (+20), driving 41 min, cycling 50 min: driving is fastest"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEvaluate_TransitSummary(t *testing.T) {
+	// Given
+	departure := time.Date(2025, 2, 10, 8, 12, 0, 0, time.UTC)
+	provider := &fakeProvider{
+		durations: []time.Duration{40 * time.Minute},
+		transitLegs: []routing.TransitLeg{
+			{Line: "Northern line", DepartureStop: "Embankment", DepartureTime: departure, ArrivalStop: "Bank"},
+			{Line: "Central line", DepartureStop: "Bank", DepartureTime: departure.Add(10 * time.Minute), ArrivalStop: "Liverpool Street"},
+		},
+	}
+	notifier := &fakeNotifier{}
+	evaluator := NewEvaluator(testRule(), provider, notifier, nil, nil)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC))
+
+	// Then
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is greater than 30 minutes: currently scheduled to take 40 minutes. " +
+		"Northern line 08:12 → Bank, Central line 08:22 → Liverpool Street, 1 change"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEvaluate_AlternativeRoutes(t *testing.T) {
+	// Given
+	provider := &fakeProvider{
+		durations: []time.Duration{40 * time.Minute},
+		alternatives: []routing.Journey{
+			{Duration: 45 * time.Minute, Description: "A40"},
+			{Duration: 52 * time.Minute, TransitLegs: []routing.TransitLeg{{Line: "Jubilee line", DepartureTime: time.Date(2025, 2, 10, 8, 5, 0, 0, time.UTC), ArrivalStop: "Westminster"}}},
+			{Duration: 60 * time.Minute, Description: "A4"},
+		},
+	}
+	notifier := &fakeNotifier{}
+	rule := testRule()
+	rule.AlternativeRoutes = true
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC))
+
+	// Then
+	if !provider.requests[0].Alternatives {
+		t.Error("Expected alternative routes to be requested")
+	}
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is greater than 30 minutes: currently scheduled to take 40 minutes. " +
+		"Other routes: 45 min via A40, 52 min (Jubilee line 08:05 → Westminster, no changes)"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+}
+
+func TestEvaluate_Waypoints(t *testing.T) {
+	// Given
+	provider := &fakeProvider{
+		durations:     []time.Duration{41 * time.Minute},
+		modeDurations: map[routing.TravelMode]time.Duration{routing.TravelModeDrive: 12 * time.Minute},
+	}
+	notifier := &fakeNotifier{}
+	rule := testRule()
+	nursery := config.Location{Name: "Nursery", Latitude: 51.507, Longitude: -0.1301}
+	rule.Waypoints = []config.Waypoint{{Location: nursery, TravelMode: "DRIVE", DwellMinutes: 5}}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+	now := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// When
+	actual := evaluateAt(evaluator, notifier, now)
+
+	// Then
+	expected := "Travel time between 10 Downing Street and Palace of Westminster is greater than 30 minutes: currently scheduled to take 58 minutes. " +
+		"10 Downing Street → Nursery 12 min driving, 5 min at Nursery, Nursery → Palace of Westminster 41 min transit"
+	if len(actual) != 1 || actual[0] != expected {
+		t.Errorf("Expected %q, got %q", expected, actual)
+	}
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected a request per leg, got %+v", provider.requests)
+	}
+	if provider.requests[0].Destination != (routing.Location{Latitude: 51.507, Longitude: -0.1301}) || !provider.requests[0].DepartureTime.IsZero() {
+		t.Errorf("Expected the first leg to the nursery leaving now, got %+v", provider.requests[0])
+	}
+	if provider.requests[1].TravelMode != routing.TravelModeTransit || !provider.requests[1].DepartureTime.Equal(now.Add(17*time.Minute)) {
+		t.Errorf("Expected the second leg by transit after dropping off, got %+v", provider.requests[1])
+	}
+}
+
+func TestEvaluate_WaypointsDepartInRuleTimezone(t *testing.T) {
+	// Given
+	provider := &fakeProvider{
+		durations:     []time.Duration{41 * time.Minute},
+		modeDurations: map[routing.TravelMode]time.Duration{routing.TravelModeDrive: 12 * time.Minute},
+	}
+	notifier := &fakeNotifier{}
+	rule := testRule()
+	rule.Timezone = "Europe/London"
+	rule.Waypoints = []config.Waypoint{{Location: config.Location{Name: "Nursery", Latitude: 51.507, Longitude: -0.1301}, TravelMode: "DRIVE"}}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+	now := time.Date(2025, 7, 1, 7, 0, 0, 0, time.UTC) // 08:00 in London (BST)
+
+	// When
+	evaluateAt(evaluator, notifier, now)
+
+	// Then
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected a request per leg, got %+v", provider.requests)
+	}
+	departure := provider.requests[1].DepartureTime
+	if departure.Location().String() != "Europe/London" || departure.Format("15:04") != "08:12" {
+		t.Errorf("Expected the second leg to depart at 08:12 London time, got %v", departure)
+	}
+}
+
+func TestEvaluate_RouteModifiersAndTransitPreferences(t *testing.T) {
+	// Given
+	provider := &fakeProvider{durations: []time.Duration{20 * time.Minute}}
+	notifier := &fakeNotifier{}
+	rule := testRule()
+	rule.RouteModifiers = config.RouteModifiers{AvoidTolls: true}
+	rule.TransitPreferences = config.TransitPreferences{AllowedModes: []string{"TRAIN"}, RoutingPreference: "LESS_WALKING"}
+	evaluator := NewEvaluator(rule, provider, notifier, nil, nil)
+
+	// When
+	evaluateAt(evaluator, notifier, time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC))
+
+	// Then
+	request := provider.requests[0]
+	if request.RouteModifiers != (routing.RouteModifiers{AvoidTolls: true}) {
+		t.Errorf("Expected tolls to be avoided, got %+v", request.RouteModifiers)
+	}
+	if !reflect.DeepEqual(request.TransitPreferences, routing.TransitPreferences{AllowedModes: []string{"TRAIN"}, RoutingPreference: "LESS_WALKING"}) {
+		t.Errorf("Expected transit preferences to be passed on, got %+v", request.TransitPreferences)
+	}
+}
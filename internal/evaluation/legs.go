@@ -0,0 +1,75 @@
+package evaluation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"wayfarer/internal/config"
+	"wayfarer/internal/routing"
+)
+
+// leg is one part of a journey with waypoints, followed by the dwell time at its destination
+type leg struct {
+	origin      config.Location
+	destination config.Location
+	travelMode  routing.TravelMode
+	dwell       time.Duration
+}
+
+// newLegs splits the journey of a rule at its waypoints, returning no legs for a rule without waypoints
+func newLegs(rule config.Rule, travelMode routing.TravelMode) []leg {
+	if len(rule.Waypoints) == 0 {
+		return nil
+	}
+	var legs []leg
+	origin := rule.Origin
+	for _, waypoint := range rule.Waypoints {
+		legMode := travelMode
+		if waypoint.TravelMode != "" {
+			legMode, _ = routing.ParseTravelMode(waypoint.TravelMode)
+		}
+		legs = append(legs, leg{
+			origin:      origin,
+			destination: waypoint.Location,
+			travelMode:  legMode,
+			dwell:       time.Duration(waypoint.DwellMinutes) * time.Minute,
+		})
+		origin = waypoint.Location
+	}
+	return append(legs, leg{origin: origin, destination: rule.Destination, travelMode: travelMode})
+}
+
+// fetchLegs fetches the legs in turn, each departing once the previous one has arrived and its dwell time has passed,
+// and combines them into a single journey. The breakdown describes each leg, e.g.
+// "Home → Nursery 12 min driving, 5 min at Nursery, Nursery → Office 41 min transit".
+func (e *Evaluator) fetchLegs(ctx context.Context, request routing.Request, now time.Time) (*routing.Journey, string, error) {
+	departure := request.DepartureTime
+	if departure.IsZero() {
+		// In the rule's timezone, as some providers send the local time of day
+		departure = now.In(e.timezone)
+	}
+
+	combined := &routing.Journey{}
+	var descriptions []string
+	for i, leg := range e.legs {
+		legRequest := e.newRequest(leg.origin, leg.destination, leg.travelMode)
+		if i > 0 || !request.DepartureTime.IsZero() {
+			legRequest.DepartureTime = departure.Add(combined.Duration)
+		}
+		journey, err := e.provider.FetchJourney(ctx, legRequest)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch the leg from %s to %s: %w", leg.origin.Name, leg.destination.Name, err)
+		}
+
+		combined.Duration += journey.Duration + leg.dwell
+		combined.TransitLegs = append(combined.TransitLegs, journey.TransitLegs...)
+		combined.WalkingDuration += journey.WalkingDuration
+		descriptions = append(descriptions, fmt.Sprintf("%s → %s %.0f min %s",
+			leg.origin.Name, leg.destination.Name, journey.Duration.Minutes(), travelModeNames[leg.travelMode]))
+		if leg.dwell > 0 {
+			descriptions = append(descriptions, fmt.Sprintf("%.0f min at %s", leg.dwell.Minutes(), leg.destination.Name))
+		}
+	}
+	return combined, strings.Join(descriptions, ", "), nil
+}
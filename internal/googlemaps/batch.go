@@ -0,0 +1,252 @@
+package googlemaps
+
+import (
+	"cloud.google.com/go/maps/routing/apiv2/routingpb"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/googleapis/gax-go/v2/callctx"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"slices"
+	"sync"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+const (
+	// How long requests are collected for a batch, so evaluations scheduled at the same minute end up in the same one
+	batchWindow = 500 * time.Millisecond
+	// Google limits traffic aware route matrix requests to 100 origins times destinations
+	maxMatrixElements = 100
+	matrixFieldMask   = "originIndex,destinationIndex,duration,status,condition"
+)
+
+// batchKey groups the requests which can share a route matrix request
+type batchKey struct {
+	travelMode     routing.TravelMode
+	departureTime  int64 // Unix nanoseconds, or 0 for now
+	routeModifiers routing.RouteModifiers
+}
+
+type batchedRequest struct {
+	request routing.Request
+	result  chan batchResult
+}
+
+type batchResult struct {
+	journey *routing.Journey
+	err     error
+}
+
+// BatchingRoutingService collects the requests made at the same time and computes those sharing an origin or a
+// destination with a single route matrix request, so rules sharing origins or destinations cost fewer API calls.
+// Route matrix elements are billed like routes, so only requests which make up a row or column of the matrix are
+// combined. Requests needing more than the duration, i.e. transit journeys, arrival times and alternative routes, are
+// computed on their own.
+type BatchingRoutingService struct {
+	*MapsRoutingService
+	window time.Duration
+	ctx    context.Context // Cancelled on Close, so batches in flight don't outlive the service
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[batchKey][]batchedRequest
+}
+
+func NewBatchingRoutingService(service *MapsRoutingService) *BatchingRoutingService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BatchingRoutingService{
+		MapsRoutingService: service,
+		window:             batchWindow,
+		ctx:                ctx,
+		cancel:             cancel,
+		pending:            make(map[batchKey][]batchedRequest),
+	}
+}
+
+// Close cancels the batches in flight and closes the client
+func (s *BatchingRoutingService) Close() error {
+	s.cancel()
+	return s.MapsRoutingService.Close()
+}
+
+func (s *BatchingRoutingService) FetchJourney(ctx context.Context, request routing.Request) (*routing.Journey, error) {
+	if request.TravelMode == routing.TravelModeTransit || request.Alternatives || !request.ArrivalTime.IsZero() {
+		return s.MapsRoutingService.FetchJourney(ctx, request)
+	}
+
+	result := make(chan batchResult, 1)
+	s.enqueue(batchedRequest{request: request, result: result})
+	select {
+	case r := <-result:
+		return r.journey, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds the request to the pending batch, starting a new batch if there isn't one
+func (s *BatchingRoutingService) enqueue(request batchedRequest) {
+	key := batchKey{travelMode: request.request.TravelMode, routeModifiers: request.request.RouteModifiers}
+	if !request.request.DepartureTime.IsZero() {
+		key.departureTime = request.request.DepartureTime.UnixNano()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests, started := s.pending[key]
+	s.pending[key] = append(requests, request)
+	if !started {
+		time.AfterFunc(s.window, func() { s.flush(key) })
+	}
+}
+
+// flush computes the batch once its window has passed. The results are sent to the buffered result channels, so a
+// request given up on doesn't block the others.
+func (s *BatchingRoutingService) flush(key batchKey) {
+	s.mu.Lock()
+	requests := s.pending[key]
+	delete(s.pending, key)
+	s.mu.Unlock()
+
+	for _, group := range groupRequests(requests, maxMatrixElements) {
+		if len(group) == 1 {
+			journey, err := s.MapsRoutingService.FetchJourney(s.ctx, group[0].request)
+			group[0].result <- batchResult{journey: journey, err: err}
+			continue
+		}
+		s.computeMatrix(s.ctx, group)
+	}
+}
+
+// groupRequests groups the requests sharing an origin or a destination, largest groups first, so each matrix has a
+// single origin or destination and as many elements as distinct requests. Groups are split to stay within the
+// maximum number of elements. Requests sharing neither are returned on their own.
+func groupRequests(requests []batchedRequest, maxElements int) [][]batchedRequest {
+	var candidates [][]int // Indexes of the requests sharing each origin, then each destination
+	collect := func(location func(routing.Request) routing.Location) {
+		indexes := make(map[routing.Location]int) // Into candidates
+		for i, request := range requests {
+			key := location(request.request)
+			if _, ok := indexes[key]; !ok {
+				indexes[key] = len(candidates)
+				candidates = append(candidates, nil)
+			}
+			candidates[indexes[key]] = append(candidates[indexes[key]], i)
+		}
+	}
+	collect(func(request routing.Request) routing.Location { return request.Origin })
+	collect(func(request routing.Request) routing.Location { return request.Destination })
+	slices.SortStableFunc(candidates, func(a, b []int) int { return len(b) - len(a) })
+
+	var groups [][]batchedRequest
+	grouped := make([]bool, len(requests))
+	for _, candidate := range candidates {
+		var group []batchedRequest
+		for _, i := range candidate {
+			if !grouped[i] {
+				group = append(group, requests[i])
+			}
+		}
+		if len(group) < 2 {
+			continue
+		}
+		for _, i := range candidate {
+			grouped[i] = true
+		}
+		for chunk := range slices.Chunk(group, maxElements) {
+			groups = append(groups, chunk)
+		}
+	}
+	for i, request := range requests {
+		if !grouped[i] {
+			groups = append(groups, []batchedRequest{request})
+		}
+	}
+	return groups
+}
+
+// computeMatrix computes the requests, which share a batch key, with one route matrix request of their distinct
+// origins and destinations
+func (s *MapsRoutingService) computeMatrix(ctx context.Context, requests []batchedRequest) {
+	first := requests[0].request
+	travelMode := routingpb.RouteTravelMode(routingpb.RouteTravelMode_value[string(first.TravelMode)])
+	req := &routingpb.ComputeRouteMatrixRequest{TravelMode: travelMode}
+	if isMotorised(travelMode) {
+		req.RoutingPreference = routingpb.RoutingPreference_TRAFFIC_AWARE_OPTIMAL
+	}
+	if !first.DepartureTime.IsZero() {
+		req.DepartureTime = timestamppb.New(first.DepartureTime)
+	}
+
+	originIndexes := make(map[routing.Location]int32)
+	destinationIndexes := make(map[routing.Location]int32)
+	for _, request := range requests {
+		if _, ok := originIndexes[request.request.Origin]; !ok {
+			originIndexes[request.request.Origin] = int32(len(req.Origins))
+			origin := &routingpb.RouteMatrixOrigin{Waypoint: toWaypoint(request.request.Origin)}
+			if isMotorised(travelMode) {
+				origin.RouteModifiers = toRouteModifiers(first.RouteModifiers)
+			}
+			req.Origins = append(req.Origins, origin)
+		}
+		if _, ok := destinationIndexes[request.request.Destination]; !ok {
+			destinationIndexes[request.request.Destination] = int32(len(req.Destinations))
+			req.Destinations = append(req.Destinations, &routingpb.RouteMatrixDestination{Waypoint: toWaypoint(request.request.Destination)})
+		}
+	}
+
+	elements, err := s.fetchMatrix(ctx, req)
+	for _, request := range requests {
+		if err != nil {
+			request.result <- batchResult{err: err}
+			continue
+		}
+		element, ok := elements[[2]int32{originIndexes[request.request.Origin], destinationIndexes[request.request.Destination]}]
+		request.result <- toBatchResult(element, ok)
+	}
+}
+
+// fetchMatrix returns the streamed elements by origin and destination index. Each attempt reads the whole stream, so
+// an interrupted stream is requested again.
+func (s *MapsRoutingService) fetchMatrix(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest) (map[[2]int32]*routingpb.RouteMatrixElement, error) {
+	ctx = callctx.SetHeaders(ctx, callctx.XGoogFieldMaskHeader, matrixFieldMask)
+	var elements map[[2]int32]*routingpb.RouteMatrixElement
+	err := s.invoke(ctx, func(ctx context.Context) error {
+		stream, err := s.client.ComputeRouteMatrix(ctx, req)
+		if err != nil {
+			return err
+		}
+		elements = make(map[[2]int32]*routingpb.RouteMatrixElement)
+		for {
+			element, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			elements[[2]int32{element.GetOriginIndex(), element.GetDestinationIndex()}] = element
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("API request to compute route matrix failed: %w", err)
+	}
+	return elements, nil
+}
+
+func toBatchResult(element *routingpb.RouteMatrixElement, ok bool) batchResult {
+	if !ok || element.Condition == routingpb.RouteMatrixElementCondition_ROUTE_NOT_FOUND {
+		return batchResult{err: errors.New("no routes found")}
+	}
+	if codes.Code(element.GetStatus().GetCode()) != codes.OK {
+		return batchResult{err: fmt.Errorf("failed to compute route: %s", element.GetStatus().GetMessage())}
+	}
+	if element.GetDuration() == nil {
+		// Like routes without a duration, rather than a journey taking no time
+		return batchResult{err: errors.New("no routes found")}
+	}
+	return batchResult{journey: &routing.Journey{Duration: element.GetDuration().AsDuration()}}
+}
@@ -0,0 +1,283 @@
+package googlemaps
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/maps/routing/apiv2/routingpb"
+	"github.com/googleapis/gax-go/v2"
+	"github.com/googleapis/gax-go/v2/callctx"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"wayfarer/internal/routing"
+)
+
+// fakeMatrixStream streams the elements, then io.EOF
+type fakeMatrixStream struct {
+	grpc.ClientStream
+	elements []*routingpb.RouteMatrixElement
+}
+
+func (f *fakeMatrixStream) Recv() (*routingpb.RouteMatrixElement, error) {
+	if len(f.elements) == 0 {
+		return nil, io.EOF
+	}
+	element := f.elements[0]
+	f.elements = f.elements[1:]
+	return element, nil
+}
+
+// fetchConcurrently fetches the journeys at the same time, as evaluations scheduled at the same minute do
+func fetchConcurrently(service routing.Provider, requests []routing.Request) ([]*routing.Journey, []error) {
+	journeys := make([]*routing.Journey, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		wg.Go(func() {
+			journeys[i], errs[i] = service.FetchJourney(context.Background(), request)
+		})
+	}
+	wg.Wait()
+	return journeys, errs
+}
+
+func TestBatchingRoutingService_SharesMatrixRequest(t *testing.T) {
+	// Given
+	office := routing.Location{Latitude: 51.513, Longitude: -0.0877}
+	homes := []routing.Location{{Latitude: 51.503, Longitude: -0.1276}, {Latitude: 51.498, Longitude: -0.1246}}
+	var mu sync.Mutex
+	var matrixRequests []*routingpb.ComputeRouteMatrixRequest
+	var fieldMask []string
+	fakeClient := &fakeRoutesClient{
+		computeRouteMatrixFunc: func(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			matrixRequests = append(matrixRequests, req)
+			fieldMask = callctx.HeadersFromContext(ctx)[callctx.XGoogFieldMaskHeader]
+			// The order of the origins depends on which request came first, so the duration is by origin
+			stream := &fakeMatrixStream{}
+			for i, origin := range req.Origins {
+				duration := 30 * time.Minute
+				if origin.Waypoint.GetLocation().GetLatLng().GetLatitude() == homes[0].Latitude {
+					duration = 20 * time.Minute
+				}
+				stream.elements = append(stream.elements, &routingpb.RouteMatrixElement{
+					OriginIndex:      proto.Int32(int32(i)),
+					DestinationIndex: proto.Int32(0),
+					Duration:         durationpb.New(duration),
+					Condition:        routingpb.RouteMatrixElementCondition_ROUTE_EXISTS,
+				})
+			}
+			return stream, nil
+		},
+	}
+	service := NewBatchingRoutingService(&MapsRoutingService{client: fakeClient})
+	service.window = 50 * time.Millisecond
+	modifiers := routing.RouteModifiers{AvoidTolls: true}
+	requests := []routing.Request{
+		{Origin: homes[0], Destination: office, TravelMode: routing.TravelModeDrive, RouteModifiers: modifiers},
+		{Origin: homes[1], Destination: office, TravelMode: routing.TravelModeDrive, RouteModifiers: modifiers},
+	}
+
+	// When
+	journeys, errs := fetchConcurrently(service, requests)
+
+	// Then
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(matrixRequests) != 1 {
+		t.Fatalf("expected a single matrix request, got %d", len(matrixRequests))
+	}
+	req := matrixRequests[0]
+	if len(req.Origins) != 2 || len(req.Destinations) != 1 {
+		t.Errorf("expected 2 origins and 1 destination, got %d and %d", len(req.Origins), len(req.Destinations))
+	}
+	if req.RoutingPreference != routingpb.RoutingPreference_TRAFFIC_AWARE_OPTIMAL || !req.Origins[0].RouteModifiers.GetAvoidTolls() {
+		t.Errorf("expected live traffic and route modifiers, got %v", req)
+	}
+	if len(fieldMask) != 1 || fieldMask[0] != matrixFieldMask {
+		t.Errorf("expected field mask %q, got %q", matrixFieldMask, fieldMask)
+	}
+	if journeys[0].Duration != 20*time.Minute || journeys[1].Duration != 30*time.Minute {
+		t.Errorf("expected 20 and 30 minutes, got %v and %v", journeys[0].Duration, journeys[1].Duration)
+	}
+}
+
+func TestBatchingRoutingService_ElementErrors(t *testing.T) {
+	// Given
+	fakeClient := &fakeRoutesClient{
+		computeRouteMatrixFunc: func(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error) {
+			stream := &fakeMatrixStream{}
+			for i, origin := range req.Origins {
+				element := &routingpb.RouteMatrixElement{OriginIndex: proto.Int32(int32(i)), DestinationIndex: proto.Int32(0)}
+				if origin.Waypoint.GetLocation().GetLatLng().GetLatitude() > 90 {
+					element.Status = &status.Status{Code: int32(codes.InvalidArgument), Message: "Invalid waypoint"}
+				} else {
+					element.Condition = routingpb.RouteMatrixElementCondition_ROUTE_NOT_FOUND
+				}
+				stream.elements = append(stream.elements, element)
+			}
+			return stream, nil
+		},
+	}
+	service := NewBatchingRoutingService(&MapsRoutingService{client: fakeClient})
+	service.window = 50 * time.Millisecond
+	office := routing.Location{Latitude: 51.513, Longitude: -0.0877}
+	requests := []routing.Request{
+		{Origin: routing.Location{Latitude: 51.503, Longitude: -0.1276}, Destination: office, TravelMode: routing.TravelModeDrive},
+		{Origin: routing.Location{Latitude: 91, Longitude: 0}, Destination: office, TravelMode: routing.TravelModeDrive},
+	}
+
+	// When
+	_, errs := fetchConcurrently(service, requests)
+
+	// Then
+	if errs[0] == nil || errs[0].Error() != "no routes found" {
+		t.Errorf("expected no routes found, got %v", errs[0])
+	}
+	if errs[1] == nil || errs[1].Error() != "failed to compute route: Invalid waypoint" {
+		t.Errorf("expected invalid waypoint error, got %v", errs[1])
+	}
+}
+
+func TestToBatchResult_MissingDuration(t *testing.T) {
+	// Given
+	element := &routingpb.RouteMatrixElement{Condition: routingpb.RouteMatrixElementCondition_ROUTE_EXISTS}
+
+	// When
+	result := toBatchResult(element, true)
+
+	// Then
+	if result.err == nil || result.err.Error() != "no routes found" {
+		t.Errorf("expected no routes found, got %v and %+v", result.err, result.journey)
+	}
+}
+
+func TestBatchingRoutingService_TransitIsNotBatched(t *testing.T) {
+	// Given
+	computeRoutesCalls := 0
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			computeRoutesCalls++
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+	}
+	service := NewBatchingRoutingService(&MapsRoutingService{client: fakeClient})
+
+	// When
+	journey, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeTransit})
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if computeRoutesCalls != 1 || journey.Duration != 10*time.Minute {
+		t.Errorf("expected the route to be computed on its own, got %d calls and %v", computeRoutesCalls, journey.Duration)
+	}
+}
+
+func TestBatchingRoutingService_UnrelatedRequestsAreComputedAlone(t *testing.T) {
+	// Given
+	var mu sync.Mutex
+	computeRoutesCalls := 0
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			computeRoutesCalls++
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+		computeRouteMatrixFunc: func(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error) {
+			t.Errorf("expected no matrix request, got %v", req)
+			return &fakeMatrixStream{}, nil
+		},
+	}
+	service := NewBatchingRoutingService(&MapsRoutingService{client: fakeClient})
+	service.window = 50 * time.Millisecond
+	home := routing.Location{Latitude: 51.503, Longitude: -0.1276}
+	office := routing.Location{Latitude: 51.513, Longitude: -0.0877}
+	requests := []routing.Request{
+		{Origin: home, Destination: office, TravelMode: routing.TravelModeDrive},
+		{Origin: office, Destination: home, TravelMode: routing.TravelModeDrive},
+	}
+
+	// When
+	_, errs := fetchConcurrently(service, requests)
+
+	// Then
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if computeRoutesCalls != 2 {
+		t.Errorf("expected each route to be computed on its own, got %d calls", computeRoutesCalls)
+	}
+}
+
+func TestBatchingRoutingService_CloseCancelsBatch(t *testing.T) {
+	// Given
+	fakeClient := &fakeRoutesClient{
+		computeRouteMatrixFunc: func(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	service := NewBatchingRoutingService(&MapsRoutingService{client: fakeClient})
+	service.window = 50 * time.Millisecond
+	office := routing.Location{Latitude: 51.513, Longitude: -0.0877}
+	requests := []routing.Request{
+		{Origin: routing.Location{Latitude: 51.503, Longitude: -0.1276}, Destination: office, TravelMode: routing.TravelModeDrive},
+		{Origin: routing.Location{Latitude: 51.498, Longitude: -0.1246}, Destination: office, TravelMode: routing.TravelModeDrive},
+	}
+	time.AfterFunc(100*time.Millisecond, func() { _ = service.Close() })
+
+	// When
+	_, errs := fetchConcurrently(service, requests)
+
+	// Then
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the batch to be cancelled, got %v", err)
+		}
+	}
+}
+
+func TestGroupRequests(t *testing.T) {
+	// Given
+	home := routing.Location{Latitude: 51.503, Longitude: -0.1276}
+	office := routing.Location{Latitude: 51.513, Longitude: -0.0877}
+	gym := routing.Location{Latitude: 51.52, Longitude: -0.1}
+	var requests []batchedRequest
+	for i := range 5 {
+		requests = append(requests, batchedRequest{request: routing.Request{Origin: routing.Location{Latitude: 51.4, Longitude: float64(i) / 100}, Destination: office}})
+	}
+	requests = append(requests,
+		batchedRequest{request: routing.Request{Origin: home, Destination: gym}},
+		batchedRequest{request: routing.Request{Origin: office, Destination: home}},
+	)
+
+	// When
+	groups := groupRequests(requests, 3)
+
+	// Then
+	var sizes []int
+	for _, group := range groups {
+		sizes = append(sizes, len(group))
+	}
+	// The 5 homes sharing the office are split to stay within 3 elements, the others share nothing
+	if !slices.Equal(sizes, []int{3, 2, 1, 1}) {
+		t.Errorf("expected groups of 3, 2, 1 and 1 requests, got %v", sizes)
+	}
+}
@@ -1,8 +1,9 @@
 package googlemaps
 
 import (
-	routing "cloud.google.com/go/maps/routing/apiv2"
+	routingapi "cloud.google.com/go/maps/routing/apiv2"
 	"cloud.google.com/go/maps/routing/apiv2/routingpb"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -11,25 +12,35 @@ import (
 	"google.golang.org/genproto/googleapis/type/latlng"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"log/slog"
-	"time"
+	"slices"
+	"wayfarer/internal/routing"
 
 	"google.golang.org/api/option"
 )
 
+// Only the fields needed are requested, transit details are billed at a higher rate so are only requested for transit
+const (
+	fieldMask        = "routes.duration"
+	transitFieldMask = fieldMask + ",routes.legs.steps.travelMode,routes.legs.steps.staticDuration,routes.legs.steps.transitDetails"
+)
+
 type RoutesClient interface {
 	ComputeRoutes(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error)
+	ComputeRouteMatrix(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error)
 	Close() error
 }
 
 type MapsRoutingService struct {
 	client RoutesClient
+	retry  RetrySettings
 }
 
-func NewMapsRoutingService(googleApiBaseUrl string, googleApiKey string) (*MapsRoutingService, error) {
+func NewMapsRoutingService(googleApiBaseUrl string, googleApiKey string, retry RetrySettings) (*MapsRoutingService, error) {
 	if googleApiBaseUrl != "" {
 		slog.Warn("Using insecure connection to custom Google Maps API", slog.String("url", googleApiBaseUrl))
-		client, err := routing.NewRoutesClient(context.Background(),
+		client, err := routingapi.NewRoutesClient(context.Background(),
 			option.WithEndpoint(googleApiBaseUrl),
 			option.WithoutAuthentication(),
 			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())))
@@ -37,38 +48,147 @@ func NewMapsRoutingService(googleApiBaseUrl string, googleApiKey string) (*MapsR
 			return nil, fmt.Errorf("failed to create Routes client: %w", err)
 		}
 
-		return &MapsRoutingService{client: client}, nil
+		return &MapsRoutingService{client: client, retry: retry}, nil
 	}
 
-	client, err := routing.NewRoutesClient(context.Background(), option.WithAPIKey(googleApiKey))
+	client, err := routingapi.NewRoutesClient(context.Background(), option.WithAPIKey(googleApiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Routes client: %w", err)
 	}
 
-	return &MapsRoutingService{client: client}, nil
+	return &MapsRoutingService{client: client, retry: retry}, nil
 }
 
 func (s *MapsRoutingService) Close() error {
 	return s.client.Close()
 }
 
-func (s *MapsRoutingService) FetchCurrentTransitTimeBetween(origin, destination *latlng.LatLng) (time.Duration, error) {
+func (s *MapsRoutingService) FetchJourney(ctx context.Context, request routing.Request) (*routing.Journey, error) {
+	travelMode := routingpb.RouteTravelMode(routingpb.RouteTravelMode_value[string(request.TravelMode)])
 	req := &routingpb.ComputeRoutesRequest{
-		Origin:      &routingpb.Waypoint{LocationType: &routingpb.Waypoint_Location{Location: &routingpb.Location{LatLng: origin}}},
-		Destination: &routingpb.Waypoint{LocationType: &routingpb.Waypoint_Location{Location: &routingpb.Location{LatLng: destination}}},
-		TravelMode:  routingpb.RouteTravelMode_TRANSIT,
+		Origin:                   toWaypoint(request.Origin),
+		Destination:              toWaypoint(request.Destination),
+		TravelMode:               travelMode,
+		ComputeAlternativeRoutes: request.Alternatives,
+	}
+	if isMotorised(travelMode) {
+		// Take live traffic into account; only supported for motorised travel modes
+		req.RoutingPreference = routingpb.RoutingPreference_TRAFFIC_AWARE_OPTIMAL
+		req.RouteModifiers = toRouteModifiers(request.RouteModifiers)
+	}
+	if travelMode == routingpb.RouteTravelMode_TRANSIT {
+		req.TransitPreferences = toTransitPreferences(request.TransitPreferences)
+	}
+	if !request.DepartureTime.IsZero() {
+		req.DepartureTime = timestamppb.New(request.DepartureTime)
+	}
+	if !request.ArrivalTime.IsZero() {
+		req.ArrivalTime = timestamppb.New(request.ArrivalTime)
 	}
 
-	ctx := callctx.SetHeaders(context.Background(), callctx.XGoogFieldMaskHeader, "routes.duration")
-	resp, err := s.client.ComputeRoutes(ctx, req)
+	mask := fieldMask
+	if travelMode == routingpb.RouteTravelMode_TRANSIT {
+		mask = transitFieldMask
+	}
+	if request.Alternatives {
+		// Tells the routes apart, e.g. by the main road
+		mask += ",routes.description"
+	}
+	ctx = callctx.SetHeaders(ctx, callctx.XGoogFieldMaskHeader, mask)
+	var resp *routingpb.ComputeRoutesResponse
+	err := s.invoke(ctx, func(ctx context.Context) error {
+		var err error
+		resp, err = s.client.ComputeRoutes(ctx, req)
+		return err
+	})
 	if err != nil {
-		return 0, fmt.Errorf("API request to compute routes failed: %w", err)
+		return nil, fmt.Errorf("API request to compute routes failed: %w", err)
 	}
 
-	if len(resp.Routes) == 0 {
-		return 0, errors.New("no routes found")
+	// The first route is the one Google recommends, which isn't always the fastest. Routes without a duration, e.g.
+	// from a partial response, can't be compared.
+	journeys := make([]routing.Journey, 0, len(resp.GetRoutes()))
+	for _, route := range resp.GetRoutes() {
+		if route.GetDuration() != nil {
+			journeys = append(journeys, toJourney(route))
+		}
+	}
+	if len(journeys) == 0 {
+		return nil, errors.New("no routes found")
+	}
+	slices.SortStableFunc(journeys, func(a, b routing.Journey) int {
+		return cmp.Compare(a.Duration, b.Duration)
+	})
+	journey := journeys[0]
+	journey.Alternatives = journeys[1:]
+	return &journey, nil
+}
+
+func toJourney(route *routingpb.Route) routing.Journey {
+	// Fields may be missing from partial responses, so they are read with the nil safe getters
+	journey := routing.Journey{
+		Duration:    route.GetDuration().AsDuration(),
+		Description: route.GetDescription(),
+	}
+	for _, leg := range route.GetLegs() {
+		for _, step := range leg.GetSteps() {
+			if step.GetTravelMode() == routingpb.RouteTravelMode_WALK {
+				journey.WalkingDuration += step.GetStaticDuration().AsDuration()
+			}
+			if details := step.GetTransitDetails(); details != nil {
+				journey.TransitLegs = append(journey.TransitLegs, toTransitLeg(details))
+			}
+		}
 	}
+	return journey
+}
 
-	duration := resp.Routes[0].Duration
-	return time.Duration(duration.Seconds) * time.Second, nil
+func toTransitLeg(details *routingpb.RouteLegStepTransitDetails) routing.TransitLeg {
+	line := details.GetTransitLine().GetName()
+	if line == "" {
+		line = details.GetTransitLine().GetNameShort()
+	}
+	stops := details.GetStopDetails()
+	return routing.TransitLeg{
+		Line:          line,
+		DepartureStop: stops.GetDepartureStop().GetName(),
+		DepartureTime: stops.GetDepartureTime().AsTime(),
+		ArrivalStop:   stops.GetArrivalStop().GetName(),
+		ArrivalTime:   stops.GetArrivalTime().AsTime(),
+	}
+}
+
+func isMotorised(travelMode routingpb.RouteTravelMode) bool {
+	return travelMode == routingpb.RouteTravelMode_DRIVE || travelMode == routingpb.RouteTravelMode_TWO_WHEELER
+}
+
+func toRouteModifiers(modifiers routing.RouteModifiers) *routingpb.RouteModifiers {
+	return &routingpb.RouteModifiers{
+		AvoidTolls:    modifiers.AvoidTolls,
+		AvoidHighways: modifiers.AvoidHighways,
+		AvoidFerries:  modifiers.AvoidFerries,
+	}
+}
+
+func toTransitPreferences(preferences routing.TransitPreferences) *routingpb.TransitPreferences {
+	transitPreferences := &routingpb.TransitPreferences{
+		RoutingPreference: routingpb.TransitPreferences_TransitRoutingPreference(
+			routingpb.TransitPreferences_TransitRoutingPreference_value[preferences.RoutingPreference]),
+	}
+	for _, mode := range preferences.AllowedModes {
+		transitPreferences.AllowedTravelModes = append(transitPreferences.AllowedTravelModes,
+			routingpb.TransitPreferences_TransitTravelMode(routingpb.TransitPreferences_TransitTravelMode_value[mode]))
+	}
+	return transitPreferences
+}
+
+func toWaypoint(location routing.Location) *routingpb.Waypoint {
+	if location.PlaceID != "" {
+		return &routingpb.Waypoint{LocationType: &routingpb.Waypoint_PlaceId{PlaceId: location.PlaceID}}
+	}
+	if location.Address != "" {
+		return &routingpb.Waypoint{LocationType: &routingpb.Waypoint_Address{Address: location.Address}}
+	}
+	latLng := &latlng.LatLng{Latitude: location.Latitude, Longitude: location.Longitude}
+	return &routingpb.Waypoint{LocationType: &routingpb.Waypoint_Location{Location: &routingpb.Location{LatLng: latLng}}}
 }
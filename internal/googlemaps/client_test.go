@@ -3,29 +3,38 @@ package googlemaps
 import (
 	"context"
 	"errors"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/maps/routing/apiv2/routingpb"
 	"github.com/googleapis/gax-go/v2"
-	"google.golang.org/genproto/googleapis/type/latlng"
+	"github.com/googleapis/gax-go/v2/callctx"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"wayfarer/internal/routing"
 )
 
 type fakeRoutesClient struct {
-	computeRoutesFunc func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error)
+	computeRoutesFunc      func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error)
+	computeRouteMatrixFunc func(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error)
 }
 
 func (f *fakeRoutesClient) ComputeRoutes(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
 	return f.computeRoutesFunc(ctx, req, opts...)
 }
 
+func (f *fakeRoutesClient) ComputeRouteMatrix(ctx context.Context, req *routingpb.ComputeRouteMatrixRequest, opts ...gax.CallOption) (routingpb.Routes_ComputeRouteMatrixClient, error) {
+	return f.computeRouteMatrixFunc(ctx, req, opts...)
+}
+
 func (f *fakeRoutesClient) Close() error {
 	return nil
 }
 
-func TestFetchCurrentTransitTimeBetween_HappyPath(t *testing.T) {
+func TestFetchJourney_HappyPath(t *testing.T) {
 	// Given
 	fakeClient := &fakeRoutesClient{
 		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
@@ -40,23 +49,26 @@ func TestFetchCurrentTransitTimeBetween_HappyPath(t *testing.T) {
 	}
 
 	service := &MapsRoutingService{client: fakeClient}
-	origin := &latlng.LatLng{Latitude: 51.503, Longitude: -0.1276}
-	destination := &latlng.LatLng{Latitude: 51.498, Longitude: -0.1246}
+	request := routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeTransit,
+	}
 
 	// When
-	duration, err := service.FetchCurrentTransitTimeBetween(origin, destination)
+	journey, err := service.FetchJourney(context.Background(), request)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
 	// Then
 	expected := 600 * time.Second
-	if duration != expected {
-		t.Errorf("expected duration %v, got %v", expected, duration)
+	if journey.Duration != expected {
+		t.Errorf("expected duration %v, got %v", expected, journey.Duration)
 	}
 }
 
-func TestFetchCurrentTransitTimeBetween_ClientError(t *testing.T) {
+func TestFetchJourney_ClientError(t *testing.T) {
 	// Given
 	fakeClient := &fakeRoutesClient{
 		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
@@ -65,11 +77,14 @@ func TestFetchCurrentTransitTimeBetween_ClientError(t *testing.T) {
 	}
 
 	service := &MapsRoutingService{client: fakeClient}
-	origin := &latlng.LatLng{Latitude: 51.503, Longitude: -0.1276}
-	destination := &latlng.LatLng{Latitude: 51.498, Longitude: -0.1246}
+	request := routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeTransit,
+	}
 
 	// When
-	_, err := service.FetchCurrentTransitTimeBetween(origin, destination)
+	_, err := service.FetchJourney(context.Background(), request)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -80,7 +95,7 @@ func TestFetchCurrentTransitTimeBetween_ClientError(t *testing.T) {
 	}
 }
 
-func TestFetchCurrentTransitTimeBetween_NoRoutesFound(t *testing.T) {
+func TestFetchJourney_NoRoutesFound(t *testing.T) {
 	// Given
 	fakeClient := &fakeRoutesClient{
 		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
@@ -91,11 +106,14 @@ func TestFetchCurrentTransitTimeBetween_NoRoutesFound(t *testing.T) {
 	}
 
 	service := &MapsRoutingService{client: fakeClient}
-	origin := &latlng.LatLng{Latitude: 51.503, Longitude: -0.1276}
-	destination := &latlng.LatLng{Latitude: 51.498, Longitude: -0.1246}
+	request := routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeTransit,
+	}
 
 	// When
-	_, err := service.FetchCurrentTransitTimeBetween(origin, destination)
+	_, err := service.FetchJourney(context.Background(), request)
 	if err == nil {
 		t.Fatal("expected error for no routes found, got nil")
 	}
@@ -106,3 +124,362 @@ func TestFetchCurrentTransitTimeBetween_NoRoutesFound(t *testing.T) {
 		t.Errorf("expected error %q, got %q", expectedError, err.Error())
 	}
 }
+
+func TestFetchJourney_PartialResponse(t *testing.T) {
+	// Given
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			return &routingpb.ComputeRoutesResponse{
+				Routes: []*routingpb.Route{
+					{Legs: []*routingpb.RouteLeg{{Steps: []*routingpb.RouteLegStep{{TravelMode: routingpb.RouteTravelMode_WALK}}}}},
+					{
+						Duration: durationpb.New(900 * time.Second),
+						Legs:     []*routingpb.RouteLeg{{Steps: []*routingpb.RouteLegStep{{TravelMode: routingpb.RouteTravelMode_WALK}}}},
+					},
+				},
+			}, nil
+		},
+	}
+
+	service := &MapsRoutingService{client: fakeClient}
+	request := routing.Request{TravelMode: routing.TravelModeTransit, Alternatives: true}
+
+	// When
+	journey, err := service.FetchJourney(context.Background(), request)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if journey.Duration != 15*time.Minute || journey.WalkingDuration != 0 || len(journey.Alternatives) != 0 {
+		t.Errorf("expected the route with a duration only, got %+v", journey)
+	}
+}
+
+func TestFetchJourney_TravelModes(t *testing.T) {
+	tests := []struct {
+		name                      string
+		request                   routing.Request
+		expectedTravelMode        routingpb.RouteTravelMode
+		expectedRoutingPreference routingpb.RoutingPreference
+	}{
+		{
+			name:                      "transit does not set routing preference",
+			request:                   routing.Request{TravelMode: routing.TravelModeTransit},
+			expectedTravelMode:        routingpb.RouteTravelMode_TRANSIT,
+			expectedRoutingPreference: routingpb.RoutingPreference_ROUTING_PREFERENCE_UNSPECIFIED,
+		},
+		{
+			name:                      "drive uses live traffic",
+			request:                   routing.Request{TravelMode: routing.TravelModeDrive},
+			expectedTravelMode:        routingpb.RouteTravelMode_DRIVE,
+			expectedRoutingPreference: routingpb.RoutingPreference_TRAFFIC_AWARE_OPTIMAL,
+		},
+		{
+			name:                      "two wheeler uses live traffic",
+			request:                   routing.Request{TravelMode: routing.TravelModeTwoWheeler},
+			expectedTravelMode:        routingpb.RouteTravelMode_TWO_WHEELER,
+			expectedRoutingPreference: routingpb.RoutingPreference_TRAFFIC_AWARE_OPTIMAL,
+		},
+		{
+			name:                      "bicycle does not set routing preference",
+			request:                   routing.Request{TravelMode: routing.TravelModeBicycle},
+			expectedTravelMode:        routingpb.RouteTravelMode_BICYCLE,
+			expectedRoutingPreference: routingpb.RoutingPreference_ROUTING_PREFERENCE_UNSPECIFIED,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			var capturedRequest *routingpb.ComputeRoutesRequest
+			fakeClient := &fakeRoutesClient{
+				computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+					capturedRequest = req
+					return &routingpb.ComputeRoutesResponse{
+						Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}},
+					}, nil
+				},
+			}
+			service := &MapsRoutingService{client: fakeClient}
+			request := tt.request
+			request.Origin = routing.Location{Latitude: 51.503, Longitude: -0.1276}
+			request.Destination = routing.Location{Latitude: 51.498, Longitude: -0.1246}
+
+			// When
+			_, err := service.FetchJourney(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Then
+			if capturedRequest.TravelMode != tt.expectedTravelMode {
+				t.Errorf("expected travel mode %v, got %v", tt.expectedTravelMode, capturedRequest.TravelMode)
+			}
+			if capturedRequest.RoutingPreference != tt.expectedRoutingPreference {
+				t.Errorf("expected routing preference %v, got %v", tt.expectedRoutingPreference, capturedRequest.RoutingPreference)
+			}
+		})
+	}
+}
+
+func TestFetchJourney_DepartureAndArrivalTimes(t *testing.T) {
+	departure := time.Date(2025, 2, 10, 8, 30, 0, 0, time.UTC)
+	arrival := time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		request           routing.Request
+		expectedDeparture *timestamppb.Timestamp
+		expectedArrival   *timestamppb.Timestamp
+	}{
+		{
+			name:    "now when neither is set",
+			request: routing.Request{TravelMode: routing.TravelModeTransit},
+		},
+		{
+			name:              "departure time",
+			request:           routing.Request{TravelMode: routing.TravelModeDrive, DepartureTime: departure},
+			expectedDeparture: timestamppb.New(departure),
+		},
+		{
+			name:            "arrival time",
+			request:         routing.Request{TravelMode: routing.TravelModeTransit, ArrivalTime: arrival},
+			expectedArrival: timestamppb.New(arrival),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			var capturedRequest *routingpb.ComputeRoutesRequest
+			fakeClient := &fakeRoutesClient{
+				computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+					capturedRequest = req
+					return &routingpb.ComputeRoutesResponse{
+						Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}},
+					}, nil
+				},
+			}
+			service := &MapsRoutingService{client: fakeClient}
+			request := tt.request
+			request.Origin = routing.Location{Latitude: 51.503, Longitude: -0.1276}
+			request.Destination = routing.Location{Latitude: 51.498, Longitude: -0.1246}
+
+			// When
+			_, err := service.FetchJourney(context.Background(), request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Then
+			if !proto.Equal(capturedRequest.DepartureTime, tt.expectedDeparture) {
+				t.Errorf("expected departure time %v, got %v", tt.expectedDeparture, capturedRequest.DepartureTime)
+			}
+			if !proto.Equal(capturedRequest.ArrivalTime, tt.expectedArrival) {
+				t.Errorf("expected arrival time %v, got %v", tt.expectedArrival, capturedRequest.ArrivalTime)
+			}
+		})
+	}
+}
+
+func TestFetchJourney_TransitDetails(t *testing.T) {
+	// Given
+	departure := time.Date(2025, 2, 10, 8, 12, 0, 0, time.UTC)
+	var fieldMask []string
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			fieldMask = callctx.HeadersFromContext(ctx)[callctx.XGoogFieldMaskHeader]
+			walk := &routingpb.RouteLegStep{TravelMode: routingpb.RouteTravelMode_WALK, StaticDuration: durationpb.New(4 * time.Minute)}
+			ride := &routingpb.RouteLegStep{
+				TravelMode: routingpb.RouteTravelMode_TRANSIT,
+				TransitDetails: &routingpb.RouteLegStepTransitDetails{
+					TransitLine: &routingpb.TransitLine{Name: "Northern line", NameShort: "N"},
+					StopDetails: &routingpb.RouteLegStepTransitDetails_TransitStopDetails{
+						DepartureStop: &routingpb.TransitStop{Name: "Embankment"},
+						DepartureTime: timestamppb.New(departure),
+						ArrivalStop:   &routingpb.TransitStop{Name: "Bank"},
+						ArrivalTime:   timestamppb.New(departure.Add(8 * time.Minute)),
+					},
+				},
+			}
+			return &routingpb.ComputeRoutesResponse{
+				Routes: []*routingpb.Route{{
+					Duration: durationpb.New(20 * time.Minute),
+					Legs:     []*routingpb.RouteLeg{{Steps: []*routingpb.RouteLegStep{walk, ride, walk}}},
+				}},
+			}, nil
+		},
+	}
+
+	service := &MapsRoutingService{client: fakeClient}
+	request := routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeTransit,
+	}
+
+	// When
+	journey, err := service.FetchJourney(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then
+	if len(fieldMask) != 1 || !strings.Contains(fieldMask[0], "routes.legs.steps.transitDetails") {
+		t.Errorf("expected transit details to be requested, got field mask %q", fieldMask)
+	}
+	expectedLeg := routing.TransitLeg{
+		Line:          "Northern line",
+		DepartureStop: "Embankment",
+		DepartureTime: departure,
+		ArrivalStop:   "Bank",
+		ArrivalTime:   departure.Add(8 * time.Minute),
+	}
+	if len(journey.TransitLegs) != 1 || journey.TransitLegs[0] != expectedLeg {
+		t.Errorf("expected transit legs %+v, got %+v", []routing.TransitLeg{expectedLeg}, journey.TransitLegs)
+	}
+	if journey.WalkingDuration != 8*time.Minute {
+		t.Errorf("expected 8 minutes walking, got %v", journey.WalkingDuration)
+	}
+}
+
+func TestFetchJourney_FieldMaskWithoutTransit(t *testing.T) {
+	// Given
+	var fieldMask []string
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			fieldMask = callctx.HeadersFromContext(ctx)[callctx.XGoogFieldMaskHeader]
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+	}
+	service := &MapsRoutingService{client: fakeClient}
+
+	// When
+	_, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeDrive})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then
+	if len(fieldMask) != 1 || fieldMask[0] != "routes.duration" {
+		t.Errorf("expected only the duration to be requested, got field mask %q", fieldMask)
+	}
+}
+
+func TestFetchJourney_AlternativeRoutes(t *testing.T) {
+	// Given
+	var received *routingpb.ComputeRoutesRequest
+	var fieldMask []string
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			received = req
+			fieldMask = callctx.HeadersFromContext(ctx)[callctx.XGoogFieldMaskHeader]
+			return &routingpb.ComputeRoutesResponse{
+				Routes: []*routingpb.Route{
+					{Duration: durationpb.New(30 * time.Minute), Description: "A40"},
+					{Duration: durationpb.New(25 * time.Minute), Description: "M4"},
+					{Duration: durationpb.New(35 * time.Minute), Description: "A4"},
+				},
+			}, nil
+		},
+	}
+	service := &MapsRoutingService{client: fakeClient}
+
+	// When
+	journey, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeDrive, Alternatives: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then
+	if !received.ComputeAlternativeRoutes {
+		t.Error("expected alternative routes to be requested")
+	}
+	if len(fieldMask) != 1 || !strings.Contains(fieldMask[0], "routes.description") {
+		t.Errorf("expected route descriptions to be requested, got field mask %q", fieldMask)
+	}
+	if journey.Duration != 25*time.Minute || journey.Description != "M4" {
+		t.Errorf("expected the fastest route, got %+v", journey)
+	}
+	if len(journey.Alternatives) != 2 || journey.Alternatives[0].Description != "A40" || journey.Alternatives[1].Description != "A4" {
+		t.Errorf("expected the other routes fastest first, got %+v", journey.Alternatives)
+	}
+}
+
+func TestFetchJourney_AddressAndPlaceID(t *testing.T) {
+	// Given
+	var received *routingpb.ComputeRoutesRequest
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			received = req
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+	}
+	service := &MapsRoutingService{client: fakeClient}
+	request := routing.Request{
+		Origin:      routing.Location{Address: "10 Downing Street, London SW1A 2AA"},
+		Destination: routing.Location{PlaceID: "ChIJJ5kaocQEdkgRKtmiHtu0p1M"},
+		TravelMode:  routing.TravelModeTransit,
+	}
+
+	// When
+	_, err := service.FetchJourney(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then
+	if received.Origin.GetAddress() != "10 Downing Street, London SW1A 2AA" {
+		t.Errorf("expected origin address, got %v", received.Origin)
+	}
+	if received.Destination.GetPlaceId() != "ChIJJ5kaocQEdkgRKtmiHtu0p1M" {
+		t.Errorf("expected destination place ID, got %v", received.Destination)
+	}
+}
+
+func TestFetchJourney_RouteModifiersAndTransitPreferences(t *testing.T) {
+	// Given
+	var requests []*routingpb.ComputeRoutesRequest
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			requests = append(requests, req)
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+	}
+	service := &MapsRoutingService{client: fakeClient}
+	request := routing.Request{
+		RouteModifiers:     routing.RouteModifiers{AvoidTolls: true, AvoidFerries: true},
+		TransitPreferences: routing.TransitPreferences{AllowedModes: []string{"SUBWAY", "TRAIN"}, RoutingPreference: "FEWER_TRANSFERS"},
+	}
+
+	// When
+	for _, travelMode := range []routing.TravelMode{routing.TravelModeDrive, routing.TravelModeTransit} {
+		request.TravelMode = travelMode
+		if _, err := service.FetchJourney(context.Background(), request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Then
+	drive, transit := requests[0], requests[1]
+	if !drive.RouteModifiers.GetAvoidTolls() || drive.RouteModifiers.GetAvoidHighways() || !drive.RouteModifiers.GetAvoidFerries() {
+		t.Errorf("unexpected route modifiers: %v", drive.RouteModifiers)
+	}
+	if drive.TransitPreferences != nil {
+		t.Errorf("expected no transit preferences when driving, got %v", drive.TransitPreferences)
+	}
+	if transit.RouteModifiers != nil {
+		t.Errorf("expected no route modifiers for transit, got %v", transit.RouteModifiers)
+	}
+	expectedModes := []routingpb.TransitPreferences_TransitTravelMode{
+		routingpb.TransitPreferences_SUBWAY,
+		routingpb.TransitPreferences_TRAIN,
+	}
+	if !slices.Equal(transit.TransitPreferences.GetAllowedTravelModes(), expectedModes) {
+		t.Errorf("expected allowed modes %v, got %v", expectedModes, transit.TransitPreferences.GetAllowedTravelModes())
+	}
+	if transit.TransitPreferences.GetRoutingPreference() != routingpb.TransitPreferences_FEWER_TRANSFERS {
+		t.Errorf("expected fewer transfers, got %v", transit.TransitPreferences.GetRoutingPreference())
+	}
+}
@@ -0,0 +1,107 @@
+package googlemaps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+type geocodeResponse struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message"`
+	Results      []struct {
+		Geometry struct {
+			Location struct {
+				Lat float64 `json:"lat"`
+				Lng float64 `json:"lng"`
+			} `json:"location"`
+		} `json:"geometry"`
+	} `json:"results"`
+}
+
+// Coordinates of a geocoded address or place ID
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Geocoder resolves addresses and place IDs to coordinates using the Geocoding API. Results are cached, so each
+// address or place ID is only looked up once.
+type Geocoder struct {
+	Url    string
+	ApiKey string
+	Logger *slog.Logger
+
+	mu    sync.Mutex
+	cache map[string]Coordinates // By query string
+}
+
+// NewGeocoder takes an API key with the Geocoding API enabled
+func NewGeocoder(apiKey string) *Geocoder {
+	return &Geocoder{
+		Url:    "https://maps.googleapis.com/maps/api/geocode/json",
+		ApiKey: apiKey,
+		Logger: slog.Default(),
+		cache:  make(map[string]Coordinates),
+	}
+}
+
+// Geocode resolves the place ID if given, or else the address
+func (g *Geocoder) Geocode(ctx context.Context, address string, placeID string) (Coordinates, error) {
+	query := url.Values{}
+	if placeID != "" {
+		query.Set("place_id", placeID)
+	} else {
+		query.Set("address", address)
+	}
+	key := query.Encode()
+
+	g.mu.Lock()
+	coordinates, cached := g.cache[key]
+	g.mu.Unlock()
+	if cached {
+		return coordinates, nil
+	}
+
+	query.Set("key", g.ApiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.Url+"?"+query.Encode(), nil)
+	if err != nil {
+		return Coordinates{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("API request to geocode failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			g.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return Coordinates{}, fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+	var response geocodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return Coordinates{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if response.Status == "ZERO_RESULTS" || (response.Status == "OK" && len(response.Results) == 0) {
+		return Coordinates{}, fmt.Errorf("no results found for %s", key)
+	}
+	if response.Status != "OK" {
+		return Coordinates{}, fmt.Errorf("API request to geocode failed: %s: %s", response.Status, response.ErrorMessage)
+	}
+
+	location := response.Results[0].Geometry.Location
+	coordinates = Coordinates{Latitude: location.Lat, Longitude: location.Lng}
+	g.mu.Lock()
+	g.cache[key] = coordinates
+	g.mu.Unlock()
+	return coordinates, nil
+}
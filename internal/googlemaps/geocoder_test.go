@@ -0,0 +1,106 @@
+package googlemaps
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGeocode_AddressIsCached(t *testing.T) {
+	// Given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("address") != "Westminster, London SW1A 0AA" || r.URL.Query().Get("key") != "test-key" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"status":"OK","results":[{"geometry":{"location":{"lat":51.4995,"lng":-0.1248}}}]}`))
+	}))
+	defer ts.Close()
+	geocoder := NewGeocoder("test-key")
+	geocoder.Url = ts.URL
+
+	// When
+	first, err := geocoder.Geocode(context.Background(), "Westminster, London SW1A 0AA", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := geocoder.Geocode(context.Background(), "Westminster, London SW1A 0AA", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then
+	expected := Coordinates{Latitude: 51.4995, Longitude: -0.1248}
+	if first != expected || second != expected {
+		t.Errorf("expected %+v, got %+v and %+v", expected, first, second)
+	}
+	if requests != 1 {
+		t.Errorf("expected the address to be geocoded once, got %d requests", requests)
+	}
+}
+
+func TestGeocode_PlaceID(t *testing.T) {
+	// Given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("place_id") != "ChIJJ5kaocQEdkgRKtmiHtu0p1M" || r.URL.Query().Has("address") {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"status":"OK","results":[{"geometry":{"location":{"lat":51.4995,"lng":-0.1248}}}]}`))
+	}))
+	defer ts.Close()
+	geocoder := NewGeocoder("test-key")
+	geocoder.Url = ts.URL
+
+	// When
+	coordinates, err := geocoder.Geocode(context.Background(), "", "ChIJJ5kaocQEdkgRKtmiHtu0p1M")
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coordinates != (Coordinates{Latitude: 51.4995, Longitude: -0.1248}) {
+		t.Errorf("unexpected coordinates: %+v", coordinates)
+	}
+}
+
+func TestGeocode_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		errMsg   string
+	}{
+		{
+			name:     "no results",
+			response: `{"status":"ZERO_RESULTS","results":[]}`,
+			errMsg:   "no results found for address=Nowhere",
+		},
+		{
+			name:     "request denied",
+			response: `{"status":"REQUEST_DENIED","error_message":"The provided API key is invalid.","results":[]}`,
+			errMsg:   "API request to geocode failed: REQUEST_DENIED: The provided API key is invalid.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer ts.Close()
+			geocoder := NewGeocoder("test-key")
+			geocoder.Url = ts.URL
+
+			// When
+			_, err := geocoder.Geocode(context.Background(), "Nowhere", "")
+
+			// Then
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("expected error %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
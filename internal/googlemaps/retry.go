@@ -0,0 +1,65 @@
+package googlemaps
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"wayfarer/internal/routing"
+
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetrySettings bound each attempt at a Routes API call and retry it on transient errors
+type RetrySettings struct {
+	Timeout     time.Duration // Per attempt, no timeout if 0
+	MaxAttempts int           // Including the first, a single attempt if 0
+}
+
+var DefaultRetrySettings = RetrySettings{Timeout: 10 * time.Second, MaxAttempts: 3}
+
+// Jittered, so requests failing at the same time aren't retried at the same time. Can be overridden in tests.
+var retryBackoff = gax.Backoff{Initial: 500 * time.Millisecond, Max: 5 * time.Second, Multiplier: 2}
+
+// Codes of errors which may not happen again, e.g. a brief outage or rate limit
+var retryableCodes = []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted}
+
+// retryer retries retryable codes with jittered exponential backoff, up to the maximum number of attempts
+type retryer struct {
+	backoff     gax.Backoff
+	attempts    int
+	maxAttempts int
+}
+
+func (r *retryer) Retry(err error) (time.Duration, bool) {
+	r.attempts++
+	if r.attempts >= r.maxAttempts || !slices.Contains(retryableCodes, status.Code(err)) {
+		return 0, false
+	}
+	return r.backoff.Pause(), true
+}
+
+// invoke makes the call, retrying it according to the retry settings. If the last attempt failed with a retryable
+// code, the error wraps routing.ErrUnavailable.
+func (s *MapsRoutingService) invoke(ctx context.Context, call func(ctx context.Context) error) error {
+	retry := gax.WithRetry(func() gax.Retryer {
+		return &retryer{
+			backoff:     retryBackoff,
+			maxAttempts: s.retry.MaxAttempts,
+		}
+	})
+	err := gax.Invoke(ctx, func(ctx context.Context, _ gax.CallSettings) error {
+		if s.retry.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.retry.Timeout)
+			defer cancel()
+		}
+		return call(ctx)
+	}, retry)
+	if err != nil && slices.Contains(retryableCodes, status.Code(err)) {
+		return fmt.Errorf("%w: %w", routing.ErrUnavailable, err)
+	}
+	return err
+}
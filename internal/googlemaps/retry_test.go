@@ -0,0 +1,120 @@
+package googlemaps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wayfarer/internal/routing"
+
+	"cloud.google.com/go/maps/routing/apiv2/routingpb"
+	"github.com/googleapis/gax-go/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func withoutBackoff(t *testing.T) {
+	original := retryBackoff
+	retryBackoff = gax.Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+	t.Cleanup(func() { retryBackoff = original })
+}
+
+// failingRoutesClient fails with the errors in turn, then returns a 10 minute route
+func failingRoutesClient(attempts *int, errs ...error) *fakeRoutesClient {
+	return &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			*attempts++
+			if *attempts <= len(errs) {
+				return nil, errs[*attempts-1]
+			}
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+	}
+}
+
+func TestFetchJourney_RetriesTransientErrors(t *testing.T) {
+	// Given
+	withoutBackoff(t)
+	attempts := 0
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+	service := &MapsRoutingService{client: failingRoutesClient(&attempts, unavailable, unavailable), retry: RetrySettings{MaxAttempts: 3}}
+
+	// When
+	journey, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeDrive})
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 3 || journey.Duration != 10*time.Minute {
+		t.Errorf("expected success on the third attempt, got %d attempts and %v", attempts, journey.Duration)
+	}
+}
+
+func TestFetchJourney_GivesUpAfterMaxAttempts(t *testing.T) {
+	// Given
+	withoutBackoff(t)
+	attempts := 0
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+	service := &MapsRoutingService{client: failingRoutesClient(&attempts, unavailable, unavailable, unavailable), retry: RetrySettings{MaxAttempts: 2}}
+
+	// When
+	_, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeDrive})
+
+	// Then
+	if !errors.Is(err, routing.ErrUnavailable) {
+		t.Errorf("expected routing service unavailable, got %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestFetchJourney_DoesNotRetryPermanentErrors(t *testing.T) {
+	// Given
+	withoutBackoff(t)
+	attempts := 0
+	invalid := status.Error(codes.InvalidArgument, "invalid waypoint")
+	service := &MapsRoutingService{client: failingRoutesClient(&attempts, invalid), retry: RetrySettings{MaxAttempts: 3}}
+
+	// When
+	_, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeDrive})
+
+	// Then
+	if err == nil || errors.Is(err, routing.ErrUnavailable) {
+		t.Errorf("expected a permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestFetchJourney_TimeoutPerAttempt(t *testing.T) {
+	// Given
+	withoutBackoff(t)
+	attempts := 0
+	fakeClient := &fakeRoutesClient{
+		computeRoutesFunc: func(ctx context.Context, req *routingpb.ComputeRoutesRequest, opts ...gax.CallOption) (*routingpb.ComputeRoutesResponse, error) {
+			attempts++
+			if attempts == 1 {
+				// Hangs until the attempt times out, like an unresponsive connection
+				<-ctx.Done()
+				return nil, status.FromContextError(ctx.Err()).Err()
+			}
+			return &routingpb.ComputeRoutesResponse{Routes: []*routingpb.Route{{Duration: durationpb.New(600 * time.Second)}}}, nil
+		},
+	}
+	service := &MapsRoutingService{client: fakeClient, retry: RetrySettings{Timeout: 50 * time.Millisecond, MaxAttempts: 2}}
+
+	// When
+	journey, err := service.FetchJourney(context.Background(), routing.Request{TravelMode: routing.TravelModeDrive})
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 || journey.Duration != 10*time.Minute {
+		t.Errorf("expected success on the second attempt, got %d attempts and %v", attempts, journey.Duration)
+	}
+}
@@ -0,0 +1,83 @@
+package gotify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"wayfarer/internal/notify"
+)
+
+type Message struct {
+	Title    string `json:"title,omitempty"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// Client sends messages to a Gotify server as an application
+type Client struct {
+	ServerUrl string // e.g. https://gotify.example.com
+	AppToken  string
+	Logger    *slog.Logger
+}
+
+func NewClient(serverUrl string, appToken string) *Client {
+	return &Client{
+		ServerUrl: serverUrl,
+		AppToken:  appToken,
+		Logger:    slog.Default(),
+	}
+}
+
+func (c *Client) Notify(ctx context.Context, message notify.Message) error {
+	payload, err := json.Marshal(Message{
+		Title:    message.Title,
+		Message:  message.Text,
+		Priority: toPriority(message.Priority),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ServerUrl+"/message", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", c.AppToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+
+	c.Logger.Info("Gotify message sent successfully")
+	return nil
+}
+
+// toPriority maps to Gotify priorities, where clients usually alert loudly from 8 upwards
+func toPriority(priority notify.Priority) int {
+	switch priority {
+	case notify.PriorityLow:
+		return 2
+	case notify.PriorityHigh:
+		return 8
+	case notify.PriorityUrgent:
+		return 10
+	default:
+		return 5
+	}
+}
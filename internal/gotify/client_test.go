@@ -0,0 +1,63 @@
+package gotify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+func TestNotify_Success(t *testing.T) {
+	// given
+	var received Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/message" {
+			t.Errorf("expected URL path %q, got %q", "/message", r.URL.Path)
+		}
+		if r.Header.Get("X-Gotify-Key") != "APP_TOKEN" {
+			t.Errorf("unexpected app token %q", r.Header.Get("X-Gotify-Key"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "APP_TOKEN")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Title: "Travel time", Text: "Hello, world!", Priority: notify.PriorityHigh})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := Message{Title: "Travel time", Message: "Hello, world!", Priority: 8}
+	if received != expected {
+		t.Errorf("expected message %+v, got %+v", expected, received)
+	}
+}
+
+func TestNotify_Non200Response(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"Unauthorized","errorCode":401}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "WRONG_TOKEN")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	expectedErr := "bad status code received: 401"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
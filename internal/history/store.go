@@ -0,0 +1,103 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Measurement is the result of a single evaluation of a rule
+type Measurement struct {
+	RuleID     int           `json:"rule_id"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Provider   string        `json:"provider"`
+	TravelMode string        `json:"travel_mode"`
+	Duration   time.Duration `json:"duration"`
+	Threshold  time.Duration `json:"threshold"`
+	Notified   bool          `json:"notified"` // Whether a notification was sent for this evaluation
+}
+
+// Measurements are stored in a bucket per rule, keyed by timestamp so they can be read back in order
+var measurementsBucket = []byte("measurements")
+
+// Store persists measurements to an embedded bbolt database
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the database at the path, creating it if needed. Only one process can open a database at a time.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(measurementsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record stores a measurement
+func (s *Store) Record(measurement Measurement) error {
+	value, err := json.Marshal(measurement)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		rule, err := tx.Bucket(measurementsBucket).CreateBucketIfNotExists(ruleKey(measurement.RuleID))
+		if err != nil {
+			return err
+		}
+		// The sequence keeps measurements with the same timestamp apart
+		sequence, err := rule.NextSequence()
+		if err != nil {
+			return err
+		}
+		return rule.Put(measurementKey(measurement.Timestamp, sequence), value)
+	})
+}
+
+// Measurements returns the measurements of a rule taken in [from, to), oldest first
+func (s *Store) Measurements(ruleID int, from time.Time, to time.Time) ([]Measurement, error) {
+	var measurements []Measurement
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rule := tx.Bucket(measurementsBucket).Bucket(ruleKey(ruleID))
+		if rule == nil {
+			return nil
+		}
+		cursor := rule.Cursor()
+		end := measurementKey(to, 0)
+		for key, value := cursor.Seek(measurementKey(from, 0)); key != nil && bytes.Compare(key, end) < 0; key, value = cursor.Next() {
+			var measurement Measurement
+			if err := json.Unmarshal(value, &measurement); err != nil {
+				return fmt.Errorf("failed to decode measurement: %w", err)
+			}
+			measurements = append(measurements, measurement)
+		}
+		return nil
+	})
+	return measurements, err
+}
+
+func ruleKey(ruleID int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(ruleID))
+}
+
+// measurementKey sorts by time, as big-endian integers sort in the same order as their bytes
+func measurementKey(timestamp time.Time, sequence uint64) []byte {
+	key := binary.BigEndian.AppendUint64(nil, uint64(timestamp.UnixNano()))
+	return binary.BigEndian.AppendUint64(key, sequence)
+}
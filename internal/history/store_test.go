@@ -0,0 +1,106 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestStore_RecordAndReadMeasurements(t *testing.T) {
+	// given
+	store := openTestStore(t)
+	monday := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	measurements := []Measurement{
+		{RuleID: 1, Timestamp: monday.Add(10 * time.Minute), Provider: "google", TravelMode: "TRANSIT", Duration: 40 * time.Minute, Threshold: 30 * time.Minute, Notified: true},
+		{RuleID: 1, Timestamp: monday, Provider: "google", TravelMode: "TRANSIT", Duration: 25 * time.Minute, Threshold: 30 * time.Minute},
+		{RuleID: 2, Timestamp: monday, Provider: "osrm", TravelMode: "DRIVE", Duration: 15 * time.Minute, Threshold: 20 * time.Minute},
+		{RuleID: 1, Timestamp: monday.Add(24 * time.Hour), Provider: "google", TravelMode: "TRANSIT", Duration: 28 * time.Minute, Threshold: 30 * time.Minute},
+	}
+
+	// when
+	for _, measurement := range measurements {
+		if err := store.Record(measurement); err != nil {
+			t.Fatalf("failed to record measurement: %v", err)
+		}
+	}
+	got, err := store.Measurements(1, monday, monday.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("failed to read measurements: %v", err)
+	}
+
+	// then
+	if len(got) != 2 {
+		t.Fatalf("expected 2 measurements, got %d: %+v", len(got), got)
+	}
+	if got[0].Duration != 25*time.Minute || got[1].Duration != 40*time.Minute {
+		t.Errorf("expected measurements oldest first, got %+v", got)
+	}
+	if !got[1].Timestamp.Equal(monday.Add(10*time.Minute)) || got[1].Provider != "google" || got[1].TravelMode != "TRANSIT" ||
+		got[1].Threshold != 30*time.Minute || !got[1].Notified {
+		t.Errorf("unexpected measurement: %+v", got[1])
+	}
+}
+
+func TestStore_SameTimestamp(t *testing.T) {
+	// given
+	store := openTestStore(t)
+	now := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+
+	// when
+	_ = store.Record(Measurement{RuleID: 1, Timestamp: now, Duration: time.Minute})
+	_ = store.Record(Measurement{RuleID: 1, Timestamp: now, Duration: 2 * time.Minute})
+	got, err := store.Measurements(1, now, now.Add(time.Second))
+
+	// then
+	if err != nil || len(got) != 2 {
+		t.Errorf("expected both measurements to be kept, got %+v (%v)", got, err)
+	}
+}
+
+func TestStore_PersistsAcrossReopen(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "history.db")
+	now := time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC)
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	_ = store.Record(Measurement{RuleID: 1, Timestamp: now, Duration: time.Minute})
+	_ = store.Close()
+
+	// when
+	store, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+	got, err := store.Measurements(1, now, now.Add(time.Minute))
+
+	// then
+	if err != nil || len(got) != 1 {
+		t.Errorf("expected measurement to be persisted, got %+v (%v)", got, err)
+	}
+}
+
+func TestStore_UnknownRule(t *testing.T) {
+	// given
+	store := openTestStore(t)
+	now := time.Now()
+
+	// when
+	got, err := store.Measurements(42, now.Add(-time.Hour), now)
+
+	// then
+	if err != nil || len(got) != 0 {
+		t.Errorf("expected no measurements, got %+v (%v)", got, err)
+	}
+}
@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Priority of a notification, used by channels which can alert more or less urgently, e.g. push notifications
+type Priority int
+
+const (
+	PriorityLow     Priority = -1
+	PriorityDefault Priority = 0
+	PriorityHigh    Priority = 1
+	PriorityUrgent  Priority = 2
+)
+
+// Message is the content of a notification
+type Message struct {
+	Title    string // Used by channels with a subject or title, e.g. email
+	Text     string
+	Priority Priority
+}
+
+// Notifier sends notifications to a user over a single channel, e.g. Telegram or Slack
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+// ErrPartiallyDelivered is wrapped by the error of Notifiers when some channels failed but others delivered the
+// message, so it shouldn't be sent again
+var ErrPartiallyDelivered = errors.New("message delivered to some channels only")
+
+// ErrUnreachable is wrapped by the errors of channels which can't deliver messages until the user does something, e.g.
+// unblocks the bot, so retrying the message won't help
+var ErrUnreachable = errors.New("channel is unreachable")
+
+// Notifiers sends notifications over several channels
+type Notifiers []Notifier
+
+// Notify sends the message over every channel, even if some of them fail
+func (n Notifiers) Notify(ctx context.Context, message Message) error {
+	var errs []error
+	delivered := false
+	for _, notifier := range n {
+		err := notifier.Notify(ctx, message)
+		if err == nil || errors.Is(err, ErrPartiallyDelivered) {
+			delivered = true
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if delivered && len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrPartiallyDelivered, errors.Join(errs...))
+	}
+	// Only unreachable if every channel is, otherwise retrying may reach the others
+	var retryable []error
+	for _, err := range errs {
+		if !errors.Is(err, ErrUnreachable) {
+			retryable = append(retryable, err)
+		}
+	}
+	if len(retryable) > 0 {
+		return errors.Join(retryable...)
+	}
+	return errors.Join(errs...)
+}
+
+// PriorityFor maps how far a journey is over its threshold to a priority
+func PriorityFor(duration time.Duration, threshold time.Duration) Priority {
+	if threshold <= 0 || duration <= threshold {
+		return PriorityLow
+	}
+
+	overrun := float64(duration-threshold) / float64(threshold)
+	switch {
+	case overrun >= 0.5:
+		return PriorityUrgent
+	case overrun >= 0.25:
+		return PriorityHigh
+	default:
+		return PriorityDefault
+	}
+}
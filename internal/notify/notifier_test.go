@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeNotifier struct {
+	err      error
+	received []Message
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, message Message) error {
+	f.received = append(f.received, message)
+	return f.err
+}
+
+func TestNotifiers_SendsToAllChannels(t *testing.T) {
+	// given
+	first := &fakeNotifier{}
+	second := &fakeNotifier{}
+	notifiers := Notifiers{first, second}
+
+	// when
+	err := notifiers.Notify(context.Background(), Message{Text: "Hello, world!"})
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(first.received) != 1 || len(second.received) != 1 {
+		t.Errorf("expected one message per channel, got %d and %d", len(first.received), len(second.received))
+	}
+}
+
+func TestNotifiers_ContinuesAfterFailure(t *testing.T) {
+	// given
+	failing := &fakeNotifier{err: errors.New("channel unavailable")}
+	working := &fakeNotifier{}
+	notifiers := Notifiers{failing, working}
+
+	// when
+	err := notifiers.Notify(context.Background(), Message{Text: "Hello, world!"})
+
+	// then
+	if !errors.Is(err, failing.err) {
+		t.Errorf("expected error %v, got %v", failing.err, err)
+	}
+	if len(working.received) != 1 {
+		t.Errorf("expected message to be sent to the working channel, got %d", len(working.received))
+	}
+	if !errors.Is(err, ErrPartiallyDelivered) {
+		t.Errorf("expected a partial delivery, got %v", err)
+	}
+}
+
+func TestNotifiers_UnreachableOnlyIfAllChannelsAre(t *testing.T) {
+	// given
+	unreachable := &fakeNotifier{err: fmt.Errorf("bot blocked: %w", ErrUnreachable)}
+	failing := &fakeNotifier{err: errors.New("channel unavailable")}
+
+	// when
+	all := Notifiers{unreachable, unreachable}.Notify(context.Background(), Message{Text: "Hello, world!"})
+	some := Notifiers{unreachable, failing}.Notify(context.Background(), Message{Text: "Hello, world!"})
+
+	// then
+	if !errors.Is(all, ErrUnreachable) {
+		t.Errorf("expected unreachable, got %v", all)
+	}
+	if some == nil || errors.Is(some, ErrUnreachable) {
+		t.Errorf("expected a failure worth retrying, got %v", some)
+	}
+}
+
+func TestNotifiers_AllChannelsFailed(t *testing.T) {
+	// given
+	notifiers := Notifiers{
+		&fakeNotifier{err: errors.New("channel unavailable")},
+		Notifiers{&fakeNotifier{err: errors.New("webhook gone")}},
+	}
+
+	// when
+	err := notifiers.Notify(context.Background(), Message{Text: "Hello, world!"})
+
+	// then
+	if err == nil || errors.Is(err, ErrPartiallyDelivered) {
+		t.Errorf("expected the message not to be delivered, got %v", err)
+	}
+}
+
+func TestPriorityFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		duration  time.Duration
+		threshold time.Duration
+		expected  Priority
+	}{
+		{name: "within threshold", duration: 30 * time.Minute, threshold: 40 * time.Minute, expected: PriorityLow},
+		{name: "slightly over threshold", duration: 44 * time.Minute, threshold: 40 * time.Minute, expected: PriorityDefault},
+		{name: "a quarter over threshold", duration: 50 * time.Minute, threshold: 40 * time.Minute, expected: PriorityHigh},
+		{name: "half over threshold", duration: 60 * time.Minute, threshold: 40 * time.Minute, expected: PriorityUrgent},
+		{name: "no threshold", duration: 60 * time.Minute, threshold: 0, expected: PriorityLow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := PriorityFor(tt.duration, tt.threshold)
+			if actual != tt.expected {
+				t.Errorf("expected priority %d, got %d", tt.expected, actual)
+			}
+		})
+	}
+}
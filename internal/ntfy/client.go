@@ -0,0 +1,73 @@
+package ntfy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"wayfarer/internal/notify"
+)
+
+// Client publishes messages to a topic on an ntfy server
+type Client struct {
+	TopicUrl string // e.g. https://ntfy.sh/my-commute
+	Token    string // Optional access token
+	Logger   *slog.Logger
+}
+
+func NewClient(topicUrl string, token string) *Client {
+	return &Client{
+		TopicUrl: topicUrl,
+		Token:    token,
+		Logger:   slog.Default(),
+	}
+}
+
+func (c *Client) Notify(ctx context.Context, message notify.Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TopicUrl, bytes.NewBufferString(message.Text))
+	if err != nil {
+		return err
+	}
+	if message.Title != "" {
+		req.Header.Set("Title", message.Title)
+	}
+	req.Header.Set("Priority", strconv.Itoa(toPriority(message.Priority)))
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+
+	c.Logger.Info("ntfy message published successfully")
+	return nil
+}
+
+// toPriority maps to ntfy priorities, from 1 (min) to 5 (max)
+func toPriority(priority notify.Priority) int {
+	switch priority {
+	case notify.PriorityLow:
+		return 2
+	case notify.PriorityHigh:
+		return 4
+	case notify.PriorityUrgent:
+		return 5
+	default:
+		return 3
+	}
+}
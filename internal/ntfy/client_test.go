@@ -0,0 +1,92 @@
+package ntfy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+func TestNotify_Success(t *testing.T) {
+	// given
+	var received *http.Request
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		content, _ := io.ReadAll(r.Body)
+		body = string(content)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id":"abc","event":"message"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL+"/commute", "tk_secret")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Title: "Travel time", Text: "Hello, world!", Priority: notify.PriorityUrgent})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	if received.URL.Path != "/commute" {
+		t.Errorf("expected URL path %q, got %q", "/commute", received.URL.Path)
+	}
+	if body != "Hello, world!" {
+		t.Errorf("expected body %q, got %q", "Hello, world!", body)
+	}
+	if received.Header.Get("Title") != "Travel time" {
+		t.Errorf("expected title %q, got %q", "Travel time", received.Header.Get("Title"))
+	}
+	if received.Header.Get("Priority") != "5" {
+		t.Errorf("expected priority 5, got %q", received.Header.Get("Priority"))
+	}
+	if received.Header.Get("Authorization") != "Bearer tk_secret" {
+		t.Errorf("unexpected authorization header %q", received.Header.Get("Authorization"))
+	}
+}
+
+func TestNotify_WithoutToken(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no authorization header, got %q", r.Header.Get("Authorization"))
+		}
+		if r.Header.Get("Priority") != "3" {
+			t.Errorf("expected default priority 3, got %q", r.Header.Get("Priority"))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestNotify_Non200Response(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	expectedErr := "bad status code received: 403"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
@@ -0,0 +1,165 @@
+package opentripplanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+const planQuery = `query Plan($from: InputCoordinates!, $to: InputCoordinates!, $date: String, $time: String, $arriveBy: Boolean, $modes: [TransportMode]) {
+  plan(from: $from, to: $to, date: $date, time: $time, arriveBy: $arriveBy, transportModes: $modes) {
+    itineraries {
+      duration
+    }
+    routingErrors {
+      code
+      description
+    }
+  }
+}`
+
+type coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type transportMode struct {
+	Mode string `json:"mode"`
+}
+
+type planVariables struct {
+	From     coordinates     `json:"from"`
+	To       coordinates     `json:"to"`
+	Date     string          `json:"date,omitempty"`
+	Time     string          `json:"time,omitempty"`
+	ArriveBy bool            `json:"arriveBy"`
+	Modes    []transportMode `json:"modes"`
+}
+
+type graphQLRequest struct {
+	Query     string        `json:"query"`
+	Variables planVariables `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data struct {
+		Plan struct {
+			Itineraries []struct {
+				Duration int64 `json:"duration"` // Seconds
+			} `json:"itineraries"`
+			RoutingErrors []struct {
+				Code        string `json:"code"`
+				Description string `json:"description"`
+			} `json:"routingErrors"`
+		} `json:"plan"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Client computes journeys using the GraphQL API of a self-hosted OpenTripPlanner instance
+type Client struct {
+	Url    string
+	Logger *slog.Logger
+}
+
+// NewClient takes the URL of the GraphQL endpoint, e.g. "http://localhost:8080/otp/gtfs/v1"
+func NewClient(url string) *Client {
+	return &Client{
+		Url:    url,
+		Logger: slog.Default(),
+	}
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) FetchJourney(ctx context.Context, request routing.Request) (*routing.Journey, error) {
+	modes, err := toTransportModes(request.TravelMode)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := planVariables{
+		From:  coordinates{Lat: request.Origin.Latitude, Lon: request.Origin.Longitude},
+		To:    coordinates{Lat: request.Destination.Latitude, Lon: request.Destination.Longitude},
+		Modes: modes,
+	}
+	// OpenTripPlanner interprets dates and times in the timezone of its transit data, defaulting to now
+	if !request.DepartureTime.IsZero() {
+		variables.Date = request.DepartureTime.Format(time.DateOnly)
+		variables.Time = request.DepartureTime.Format("15:04")
+	}
+	if !request.ArrivalTime.IsZero() {
+		variables.Date = request.ArrivalTime.Format(time.DateOnly)
+		variables.Time = request.ArrivalTime.Format("15:04")
+		variables.ArriveBy = true
+	}
+
+	payload, err := json.Marshal(graphQLRequest{Query: planQuery, Variables: variables})
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("API request to plan journey failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+
+	var response graphQLResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("API request to plan journey failed: %s", response.Errors[0].Message)
+	}
+
+	plan := response.Data.Plan
+	if len(plan.Itineraries) == 0 {
+		if len(plan.RoutingErrors) > 0 {
+			return nil, fmt.Errorf("no routes found: %s", plan.RoutingErrors[0].Description)
+		}
+		return nil, errors.New("no routes found")
+	}
+
+	return &routing.Journey{Duration: time.Duration(plan.Itineraries[0].Duration) * time.Second}, nil
+}
+
+func toTransportModes(travelMode routing.TravelMode) ([]transportMode, error) {
+	switch travelMode {
+	case routing.TravelModeTransit:
+		return []transportMode{{Mode: "TRANSIT"}, {Mode: "WALK"}}, nil
+	case routing.TravelModeWalk:
+		return []transportMode{{Mode: "WALK"}}, nil
+	case routing.TravelModeBicycle:
+		return []transportMode{{Mode: "BICYCLE"}}, nil
+	case routing.TravelModeDrive:
+		return []transportMode{{Mode: "CAR"}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", routing.ErrUnsupportedTravelMode, travelMode)
+	}
+}
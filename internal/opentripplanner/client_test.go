@@ -0,0 +1,161 @@
+package opentripplanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+func newTestRequest() routing.Request {
+	return routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeTransit,
+	}
+}
+
+func TestFetchJourney_HappyPath(t *testing.T) {
+	// given
+	var received graphQLRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":{"plan":{"itineraries":[{"duration":1500},{"duration":1800}],"routingErrors":[]}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	journey, err := client.FetchJourney(context.Background(), newTestRequest())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	if journey.Duration != 25*time.Minute {
+		t.Errorf("expected duration %v, got %v", 25*time.Minute, journey.Duration)
+	}
+	if received.Variables.From != (coordinates{Lat: 51.503, Lon: -0.1276}) {
+		t.Errorf("unexpected origin: %+v", received.Variables.From)
+	}
+	if received.Variables.To != (coordinates{Lat: 51.498, Lon: -0.1246}) {
+		t.Errorf("unexpected destination: %+v", received.Variables.To)
+	}
+	if received.Variables.Date != "" || received.Variables.Time != "" || received.Variables.ArriveBy {
+		t.Errorf("expected journey to be planned for now, got %+v", received.Variables)
+	}
+	expectedModes := []transportMode{{Mode: "TRANSIT"}, {Mode: "WALK"}}
+	if len(received.Variables.Modes) != len(expectedModes) || received.Variables.Modes[0] != expectedModes[0] || received.Variables.Modes[1] != expectedModes[1] {
+		t.Errorf("expected modes %+v, got %+v", expectedModes, received.Variables.Modes)
+	}
+}
+
+func TestFetchJourney_ArrivalTime(t *testing.T) {
+	// given
+	var received graphQLRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"data":{"plan":{"itineraries":[{"duration":1500}]}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	request := newTestRequest()
+	request.ArrivalTime = time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), request)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	if received.Variables.Date != "2025-02-10" || received.Variables.Time != "09:00" || !received.Variables.ArriveBy {
+		t.Errorf("expected arrival by 2025-02-10 09:00, got %+v", received.Variables)
+	}
+}
+
+func TestFetchJourney_Non200Response(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), newTestRequest())
+
+	// then
+	expectedErr := "bad status code received: 500"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
+
+func TestFetchJourney_GraphQLError(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errors":[{"message":"Validation error"}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), newTestRequest())
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "Validation error") {
+		t.Errorf("expected error to contain 'Validation error', got %v", err)
+	}
+}
+
+func TestFetchJourney_NoRoutesFound(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"plan":{"itineraries":[],"routingErrors":[{"code":"NO_TRANSIT_CONNECTION","description":"No connection was found"}]}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), newTestRequest())
+
+	// then
+	expectedErr := "no routes found: No connection was found"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
+
+func TestFetchJourney_UnsupportedTravelMode(t *testing.T) {
+	// given
+	client := NewClient("http://localhost:0")
+	request := newTestRequest()
+	request.TravelMode = routing.TravelModeTwoWheeler
+
+	// when
+	_, err := client.FetchJourney(context.Background(), request)
+
+	// then
+	if !errors.Is(err, routing.ErrUnsupportedTravelMode) {
+		t.Errorf("expected unsupported travel mode error, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package osrm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+type routeResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Duration float64 `json:"duration"` // Seconds
+	} `json:"routes"`
+}
+
+// Client computes journeys using the HTTP API of a self-hosted OSRM server
+type Client struct {
+	Url    string
+	Logger *slog.Logger
+}
+
+// NewClient takes the base URL of the OSRM server, e.g. "http://localhost:5000"
+func NewClient(url string) *Client {
+	return &Client{
+		Url:    url,
+		Logger: slog.Default(),
+	}
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+// FetchJourney ignores departure and arrival times because OSRM does not model traffic
+func (c *Client) FetchJourney(ctx context.Context, request routing.Request) (*routing.Journey, error) {
+	profile, err := toProfile(request.TravelMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// OSRM expects coordinates as longitude,latitude
+	url := fmt.Sprintf("%s/route/v1/%s/%f,%f;%f,%f?overview=false", c.Url, profile,
+		request.Origin.Longitude, request.Origin.Latitude, request.Destination.Longitude, request.Destination.Latitude)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API request to compute route failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	var response routeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response with status code %d: %w", resp.StatusCode, err)
+	}
+	if response.Code == "NoRoute" {
+		return nil, errors.New("no routes found")
+	}
+	if resp.StatusCode != http.StatusOK || response.Code != "Ok" {
+		return nil, fmt.Errorf("API request to compute route failed: %d %s: %s", resp.StatusCode, response.Code, response.Message)
+	}
+	if len(response.Routes) == 0 {
+		return nil, errors.New("no routes found")
+	}
+
+	return &routing.Journey{Duration: time.Duration(response.Routes[0].Duration * float64(time.Second))}, nil
+}
+
+func toProfile(travelMode routing.TravelMode) (string, error) {
+	switch travelMode {
+	case routing.TravelModeDrive:
+		return "driving", nil
+	case routing.TravelModeBicycle:
+		return "cycling", nil
+	case routing.TravelModeWalk:
+		return "foot", nil
+	default:
+		return "", fmt.Errorf("%w: %s", routing.ErrUnsupportedTravelMode, travelMode)
+	}
+}
@@ -0,0 +1,123 @@
+package osrm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+func newTestRequest() routing.Request {
+	return routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeDrive,
+	}
+}
+
+func TestFetchJourney_HappyPath(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedPath := "/route/v1/driving/-0.127600,51.503000;-0.124600,51.498000"
+		if r.URL.Path != expectedPath {
+			t.Errorf("expected URL path %q, got %q", expectedPath, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"duration":630.4,"distance":1200.1}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	journey, err := client.FetchJourney(context.Background(), newTestRequest())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := time.Duration(630.4 * float64(time.Second))
+	if journey.Duration != expected {
+		t.Errorf("expected duration %v, got %v", expected, journey.Duration)
+	}
+}
+
+func TestFetchJourney_CyclingProfile(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/route/v1/cycling/") {
+			t.Errorf("expected cycling profile, got %q", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"duration":900}]}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	request := newTestRequest()
+	request.TravelMode = routing.TravelModeBicycle
+
+	// when
+	_, err := client.FetchJourney(context.Background(), request)
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestFetchJourney_NoRoute(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"NoRoute","message":"Impossible route between points"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), newTestRequest())
+
+	// then
+	expectedErr := "no routes found"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
+
+func TestFetchJourney_ErrorResponse(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":"InvalidQuery","message":"Query string malformed"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), newTestRequest())
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "InvalidQuery") {
+		t.Errorf("expected error to contain 'InvalidQuery', got %v", err)
+	}
+}
+
+func TestFetchJourney_UnsupportedTravelMode(t *testing.T) {
+	// given
+	client := NewClient("http://localhost:0")
+	request := newTestRequest()
+	request.TravelMode = routing.TravelModeTransit
+
+	// when
+	_, err := client.FetchJourney(context.Background(), request)
+
+	// then
+	if !errors.Is(err, routing.ErrUnsupportedTravelMode) {
+		t.Errorf("expected unsupported travel mode error, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package pushover
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"wayfarer/internal/notify"
+)
+
+const DefaultApiBaseUrl = "https://api.pushover.net"
+
+// Client sends messages to a Pushover user
+type Client struct {
+	ApiBaseUrl string
+	AppToken   string
+	UserKey    string
+	Logger     *slog.Logger
+}
+
+func NewClient(apiBaseUrl string, appToken string, userKey string) *Client {
+	return &Client{
+		ApiBaseUrl: apiBaseUrl,
+		AppToken:   appToken,
+		UserKey:    userKey,
+		Logger:     slog.Default(),
+	}
+}
+
+func (c *Client) Notify(ctx context.Context, message notify.Message) error {
+	form := url.Values{
+		"token":    {c.AppToken},
+		"user":     {c.UserKey},
+		"message":  {message.Text},
+		"priority": {strconv.Itoa(toPriority(message.Priority))},
+	}
+	if message.Title != "" {
+		form.Set("title", message.Title)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.ApiBaseUrl+"/1/messages.json", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+
+	c.Logger.Info("Pushover message sent successfully")
+	return nil
+}
+
+// toPriority maps to Pushover priorities; emergency (2) is avoided as it repeats until acknowledged
+func toPriority(priority notify.Priority) int {
+	switch priority {
+	case notify.PriorityLow:
+		return -1
+	case notify.PriorityHigh, notify.PriorityUrgent:
+		return 1
+	default:
+		return 0
+	}
+}
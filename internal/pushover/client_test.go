@@ -0,0 +1,69 @@
+package pushover
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+func TestNotify_Success(t *testing.T) {
+	// given
+	var received url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1/messages.json" {
+			t.Errorf("expected URL path %q, got %q", "/1/messages.json", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		received = r.PostForm
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"status":1,"request":"abc"}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "APP_TOKEN", "USER_KEY")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Title: "Travel time", Text: "Hello, world!", Priority: notify.PriorityUrgent})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := url.Values{
+		"token":    {"APP_TOKEN"},
+		"user":     {"USER_KEY"},
+		"title":    {"Travel time"},
+		"message":  {"Hello, world!"},
+		"priority": {"1"},
+	}
+	for key, value := range expected {
+		if received.Get(key) != value[0] {
+			t.Errorf("expected %s %q, got %q", key, value[0], received.Get(key))
+		}
+	}
+}
+
+func TestNotify_Non200Response(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"user":"invalid","errors":["user identifier is invalid"],"status":0}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "APP_TOKEN", "WRONG_USER")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	expectedErr := "bad status code received: 400"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
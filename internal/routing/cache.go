@@ -0,0 +1,145 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// fetchTimeout bounds shared requests made without a deadline, so a hung provider doesn't block its callers forever
+const fetchTimeout = time.Minute
+
+type cacheEntry struct {
+	journey *Journey
+	expires time.Time
+}
+
+// CachingProvider decorates a provider, reusing the journey of an identical request for the TTL and sharing the
+// result of identical requests in flight, so rules with the same journey only cost one API call
+type CachingProvider struct {
+	Logger *slog.Logger
+
+	provider Provider
+	ttl      time.Duration
+	timeout  time.Duration // Of shared requests without a deadline, can be overridden in tests
+	group    singleflight.Group
+	requests atomic.Int64
+	misses   atomic.Int64 // Requests passed on to the provider
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+
+	now func() time.Time // Can be overridden in tests
+}
+
+func NewCachingProvider(provider Provider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		Logger:   slog.Default(),
+		provider: provider,
+		ttl:      ttl,
+		timeout:  fetchTimeout,
+		entries:  make(map[string]cacheEntry),
+		now:      time.Now,
+	}
+}
+
+// Close logs the cache statistics and closes the provider
+func (p *CachingProvider) Close() error {
+	hits, misses := p.Stats()
+	p.Logger.Info("Routing cache statistics", slog.Int64("hits", hits), slog.Int64("misses", misses))
+	return p.provider.Close()
+}
+
+// Stats returns how many requests were answered from the cache or by sharing a request in flight, and how many
+// were passed on to the provider
+func (p *CachingProvider) Stats() (hits int64, misses int64) {
+	misses = p.misses.Load()
+	return p.requests.Load() - misses, misses
+}
+
+// FetchJourney returns a copy of the journey, as it is shared with other requests. Errors aren't cached.
+func (p *CachingProvider) FetchJourney(ctx context.Context, request Request) (*Journey, error) {
+	p.requests.Add(1)
+	key := cacheKey(request)
+	if journey, ok := p.lookup(key); ok {
+		hits, misses := p.Stats()
+		p.Logger.Info("Using cached journey", slog.Int64("hits", hits), slog.Int64("misses", misses))
+		return journey, nil
+	}
+
+	// The request in flight is shared, so it shouldn't be cancelled with the context of whichever caller started it,
+	// but it still ends by the caller's deadline or the timeout
+	result := p.group.DoChan(key, func() (any, error) {
+		fetchCtx, cancel := p.fetchContext(ctx)
+		defer cancel()
+		p.misses.Add(1)
+		journey, err := p.provider.FetchJourney(fetchCtx, request)
+		if err != nil {
+			return nil, err
+		}
+		p.store(key, journey)
+		return journey, nil
+	})
+	select {
+	case r := <-result:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		journey := *r.Val.(*Journey)
+		return &journey, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchContext keeps the values of the context without its cancellation, with its deadline or else the timeout
+func (p *CachingProvider) fetchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), p.timeout)
+}
+
+func (p *CachingProvider) lookup(key string) (*Journey, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[key]
+	if !ok || !p.now().Before(entry.expires) {
+		return nil, false
+	}
+	journey := *entry.journey
+	return &journey, true
+}
+
+// store caches the journey, dropping expired entries so the cache doesn't grow with requests which aren't repeated
+func (p *CachingProvider) store(key string, journey *Journey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for k, entry := range p.entries {
+		if !now.Before(entry.expires) {
+			delete(p.entries, k)
+		}
+	}
+	p.entries[key] = cacheEntry{journey: journey, expires: now.Add(p.ttl)}
+}
+
+// cacheKey identifies identical requests. Times are keyed by instant, as the same time can be in different locations.
+func cacheKey(request Request) string {
+	var departure, arrival int64
+	if !request.DepartureTime.IsZero() {
+		departure = request.DepartureTime.UnixNano()
+	}
+	if !request.ArrivalTime.IsZero() {
+		arrival = request.ArrivalTime.UnixNano()
+	}
+	return fmt.Sprintf("%+v|%+v|%s|%d|%d|%t|%+v|%+v", request.Origin, request.Destination, request.TravelMode,
+		departure, arrival, request.Alternatives, request.RouteModifiers, request.TransitPreferences)
+}
@@ -0,0 +1,165 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	calls    atomic.Int32
+	duration time.Duration
+	err      error
+	release  chan struct{} // Optional, blocks requests until closed
+	hang     bool          // Blocks requests until their context is done, like an unresponsive server
+	hung     chan error    // Optional, receives the error of each request given up on
+}
+
+func (f *fakeProvider) FetchJourney(ctx context.Context, request Request) (*Journey, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	if f.hang {
+		<-ctx.Done()
+		if f.hung != nil {
+			f.hung <- ctx.Err()
+		}
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Journey{Duration: f.duration}, nil
+}
+
+func (f *fakeProvider) Close() error {
+	return nil
+}
+
+func TestCachingProvider_ReusesJourneyWithinTTL(t *testing.T) {
+	// Given
+	provider := &fakeProvider{duration: 25 * time.Minute}
+	cache := NewCachingProvider(provider, 2*time.Minute)
+	now := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+	request := Request{Origin: Location{Latitude: 51.503, Longitude: -0.1276}, TravelMode: TravelModeTransit}
+
+	// When
+	for _, elapsed := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		now = time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC).Add(elapsed)
+		journey, err := cache.FetchJourney(context.Background(), request)
+		if err != nil || journey.Duration != 25*time.Minute {
+			t.Fatalf("unexpected result: %v, %v", journey, err)
+		}
+	}
+
+	// Then
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("expected the journey to be fetched again once expired, got %d calls", calls)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("expected 1 hit and 2 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestCachingProvider_DifferentRequestsAreNotShared(t *testing.T) {
+	// Given
+	provider := &fakeProvider{duration: 25 * time.Minute}
+	cache := NewCachingProvider(provider, 2*time.Minute)
+	request := Request{Origin: Location{Latitude: 51.503, Longitude: -0.1276}, TravelMode: TravelModeTransit}
+	drive := request
+	drive.TravelMode = TravelModeDrive
+
+	// When
+	_, _ = cache.FetchJourney(context.Background(), request)
+	_, _ = cache.FetchJourney(context.Background(), drive)
+
+	// Then
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("expected each travel mode to be fetched, got %d calls", calls)
+	}
+}
+
+func TestCachingProvider_DeduplicatesRequestsInFlight(t *testing.T) {
+	// Given
+	provider := &fakeProvider{duration: 25 * time.Minute, release: make(chan struct{})}
+	cache := NewCachingProvider(provider, 2*time.Minute)
+	request := Request{Origin: Location{Latitude: 51.503, Longitude: -0.1276}, TravelMode: TravelModeTransit}
+
+	// When
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			if _, err := cache.FetchJourney(context.Background(), request); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+	// Let the requests reach the cache before the first one completes
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	// Then
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("expected a single request, got %d", calls)
+	}
+}
+
+func TestCachingProvider_ErrorsAreNotCached(t *testing.T) {
+	// Given
+	provider := &fakeProvider{err: errors.New("quota exceeded")}
+	cache := NewCachingProvider(provider, 2*time.Minute)
+
+	// When
+	_, first := cache.FetchJourney(context.Background(), Request{})
+	_, second := cache.FetchJourney(context.Background(), Request{})
+
+	// Then
+	if first == nil || second == nil {
+		t.Fatalf("expected errors, got %v and %v", first, second)
+	}
+	if calls := provider.calls.Load(); calls != 2 {
+		t.Errorf("expected the request to be retried, got %d calls", calls)
+	}
+}
+
+func TestCachingProvider_SharedRequestTimesOut(t *testing.T) {
+	// Given
+	provider := &fakeProvider{hang: true}
+	cache := NewCachingProvider(provider, 2*time.Minute)
+	cache.timeout = 50 * time.Millisecond
+
+	// When
+	_, err := cache.FetchJourney(context.Background(), Request{})
+
+	// Then
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the shared request to time out, got %v", err)
+	}
+}
+
+func TestCachingProvider_SharedRequestKeepsCallerDeadline(t *testing.T) {
+	// Given
+	provider := &fakeProvider{hang: true, hung: make(chan error, 1)}
+	cache := NewCachingProvider(provider, 2*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// When
+	_, _ = cache.FetchJourney(ctx, Request{})
+
+	// Then
+	select {
+	case err := <-provider.hung:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the shared request to end by the caller's deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the shared request to end by the caller's deadline")
+	}
+}
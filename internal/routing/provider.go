@@ -0,0 +1,124 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type TravelMode string
+
+const (
+	TravelModeDrive      TravelMode = "DRIVE"
+	TravelModeTransit    TravelMode = "TRANSIT"
+	TravelModeBicycle    TravelMode = "BICYCLE"
+	TravelModeWalk       TravelMode = "WALK"
+	TravelModeTwoWheeler TravelMode = "TWO_WHEELER"
+)
+
+// Location defines coordinates, or an address or place ID for providers that resolve them
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Address   string // Optional, instead of coordinates
+	PlaceID   string // Optional, instead of coordinates
+}
+
+// RouteModifiers defines features to avoid when driving
+type RouteModifiers struct {
+	AvoidTolls    bool
+	AvoidHighways bool
+	AvoidFerries  bool
+}
+
+// TransitPreferences restricts the transit journeys considered
+type TransitPreferences struct {
+	AllowedModes      []string // e.g. "BUS" or "TRAIN", defaults to all
+	RoutingPreference string   // Optional, e.g. "LESS_WALKING" or "FEWER_TRANSFERS"
+}
+
+// Request defines the journey to compute
+type Request struct {
+	Origin             Location
+	Destination        Location
+	TravelMode         TravelMode
+	DepartureTime      time.Time          // Optional, defaults to now
+	ArrivalTime        time.Time          // Optional, not supported by every provider or travel mode
+	Alternatives       bool               // Optional, compute alternative routes and return the fastest, not supported by every provider
+	RouteModifiers     RouteModifiers     // Optional, only used for motorised travel modes, not supported by every provider
+	TransitPreferences TransitPreferences // Optional, only used for transit, not supported by every provider
+}
+
+// Journey is the result of computing a route
+type Journey struct {
+	Duration        time.Duration
+	TransitLegs     []TransitLeg  // Optional, transit only, not returned by every provider
+	WalkingDuration time.Duration // Optional, transit only, total time walking to, between and from stops
+	Description     string        // Optional, e.g. the main road taken
+	Alternatives    []Journey     // Slower routes if alternatives were requested, fastest first
+}
+
+// TransitLeg is one ride on a transit vehicle
+type TransitLeg struct {
+	Line          string // e.g. "Northern line"
+	DepartureStop string
+	DepartureTime time.Time
+	ArrivalStop   string
+	ArrivalTime   time.Time
+}
+
+// Transfers returns the number of changes between transit vehicles
+func (j Journey) Transfers() int {
+	return max(len(j.TransitLegs)-1, 0)
+}
+
+// Summary describes the transit legs in the timezone, e.g. "Northern line 08:12 → Bank, 2 changes, 9 min walking",
+// or is empty if there are none
+func (j Journey) Summary(timezone *time.Location) string {
+	if len(j.TransitLegs) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, leg := range j.TransitLegs {
+		parts = append(parts, fmt.Sprintf("%s %s → %s", leg.Line, leg.DepartureTime.In(timezone).Format("15:04"), leg.ArrivalStop))
+	}
+	switch transfers := j.Transfers(); transfers {
+	case 0:
+		parts = append(parts, "no changes")
+	case 1:
+		parts = append(parts, "1 change")
+	default:
+		parts = append(parts, fmt.Sprintf("%d changes", transfers))
+	}
+	if j.WalkingDuration >= time.Minute {
+		parts = append(parts, fmt.Sprintf("%.0f min walking", j.WalkingDuration.Minutes()))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Provider computes journeys, e.g. using Google Maps or a self-hosted routing engine
+type Provider interface {
+	FetchJourney(ctx context.Context, req Request) (*Journey, error)
+	Close() error
+}
+
+// ParseTravelMode converts a travel mode from config, e.g. "DRIVE", defaulting to TRANSIT when empty
+func ParseTravelMode(mode string) (TravelMode, error) {
+	switch TravelMode(mode) {
+	case "":
+		return TravelModeTransit, nil
+	case TravelModeDrive, TravelModeTransit, TravelModeBicycle, TravelModeWalk, TravelModeTwoWheeler:
+		return TravelMode(mode), nil
+	default:
+		return "", fmt.Errorf("invalid travel mode: %s", mode)
+	}
+}
+
+// ErrUnsupportedTravelMode is returned by providers which cannot compute a journey for the requested travel mode
+var ErrUnsupportedTravelMode = errors.New("travel mode not supported by routing provider")
+
+// ErrUnavailable is wrapped by errors which may not happen again, e.g. when the routing service is briefly down
+var ErrUnavailable = errors.New("routing service unavailable")
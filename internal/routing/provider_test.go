@@ -0,0 +1,66 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTravelMode(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected TravelMode
+		wantErr  bool
+	}{
+		{mode: "", expected: TravelModeTransit},
+		{mode: "DRIVE", expected: TravelModeDrive},
+		{mode: "TWO_WHEELER", expected: TravelModeTwoWheeler},
+		{mode: "TRAVEL_MODE_UNSPECIFIED", wantErr: true},
+		{mode: "HOVERCRAFT", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			actual, err := ParseTravelMode(tt.mode)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q, got nil", tt.mode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJourneySummary(t *testing.T) {
+	departure := time.Date(2025, 2, 10, 8, 12, 0, 0, time.UTC)
+	northern := TransitLeg{Line: "Northern line", DepartureStop: "Embankment", DepartureTime: departure, ArrivalStop: "Bank"}
+	central := TransitLeg{Line: "Central line", DepartureStop: "Bank", DepartureTime: departure.Add(10 * time.Minute), ArrivalStop: "Liverpool Street"}
+	tests := []struct {
+		name     string
+		journey  Journey
+		expected string
+	}{
+		{name: "no transit legs", journey: Journey{Duration: 30 * time.Minute}, expected: ""},
+		{name: "direct", journey: Journey{TransitLegs: []TransitLeg{northern}}, expected: "Northern line 08:12 → Bank, no changes"},
+		{
+			name:     "with changes and walking",
+			journey:  Journey{TransitLegs: []TransitLeg{northern, central}, WalkingDuration: 9 * time.Minute},
+			expected: "Northern line 08:12 → Bank, Central line 08:22 → Liverpool Street, 1 change, 9 min walking",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := tt.journey.Summary(time.UTC)
+			if actual != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package scheduling
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronExpression is a parsed standard 5-field cron expression: minute, hour, day of month, month and day of week
+type CronExpression struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	// Like cron, if both day fields are restricted, a day matching either of them is used
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// ParseCron parses expressions such as "*/15 7-9 * * MON-FRI"
+func ParseCron(expression string) (*CronExpression, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields: %q", expression)
+	}
+
+	minutes, err := parseCronField(fields[0], 0, 59, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	hours, err := parseCronField(fields[1], 0, 23, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	daysOfMonth, err := parseCronField(fields[2], 1, 31, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	months, err := parseCronField(fields[3], 1, 12, monthNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	// Both 0 and 7 mean Sunday
+	daysOfWeek, err := parseCronField(fields[4], 0, 7, dayNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %w", err)
+	}
+	if daysOfWeek[7] {
+		daysOfWeek[0] = true
+	}
+
+	return &CronExpression{
+		minutes:       minutes,
+		hours:         hours,
+		daysOfMonth:   daysOfMonth,
+		months:        months,
+		daysOfWeek:    daysOfWeek,
+		anyDayOfMonth: strings.HasPrefix(fields[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, min int, max int, names map[string]int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = min, max
+		case strings.Contains(rangePart, "-"):
+			from, to, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(from, min, max, names); err != nil {
+				return nil, err
+			}
+			if end, err = parseCronValue(to, min, max, names); err != nil {
+				return nil, err
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(rangePart, min, max, names); err != nil {
+				return nil, err
+			}
+			end = start
+			if hasStep {
+				// e.g. "5/15" means every 15 starting at 5
+				end = max
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+func parseCronValue(value string, min int, max int, names map[string]int) (int, error) {
+	if named, ok := names[strings.ToUpper(value)]; ok {
+		return named, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < min || number > max {
+		return 0, fmt.Errorf("value %q must be between %d and %d", value, min, max)
+	}
+	return number, nil
+}
+
+func (c *CronExpression) matchesDay(date time.Time) bool {
+	if !c.months[int(date.Month())] {
+		return false
+	}
+	dayOfMonth := c.daysOfMonth[date.Day()]
+	dayOfWeek := c.daysOfWeek[int(date.Weekday())]
+	switch {
+	case c.anyDayOfMonth && c.anyDayOfWeek:
+		return true
+	case c.anyDayOfMonth:
+		return dayOfWeek
+	case c.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+// Next returns the first time matching the expression strictly after the given time, in the timezone
+func (c *CronExpression) Next(after time.Time, timezone *time.Location) (time.Time, error) {
+	start := after.In(timezone).Truncate(time.Minute).Add(time.Minute)
+
+	for days := 0; days < 5*366; days++ {
+		date := time.Date(start.Year(), start.Month(), start.Day()+days, 0, 0, 0, 0, timezone)
+		if !c.matchesDay(date) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !c.hours[hour] {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if !c.minutes[minute] {
+					continue
+				}
+				candidate := time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, timezone)
+				// Skip times which don't exist on this day, e.g. during a daylight saving transition
+				if candidate.Hour() != hour || candidate.Minute() != minute {
+					continue
+				}
+				if !candidate.Before(start) {
+					return candidate, nil
+				}
+			}
+		}
+	}
+	return time.Time{}, errCronNeverMatches
+}
+
+var errCronNeverMatches = errors.New("cron expression does not match any time")
@@ -0,0 +1,140 @@
+package scheduling
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseCron_Invalid(t *testing.T) {
+	expressions := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * * FUNDAY",
+		"a * * * *",
+	}
+
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			if _, err := ParseCron(expression); err == nil {
+				t.Errorf("Expected error for %q, got nil", expression)
+			}
+		})
+	}
+}
+
+func Test_CronNext(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		after      time.Time
+		timezone   *time.Location
+		expected   time.Time
+	}{
+		{
+			name:       "Every 15 minutes within the window",
+			expression: "*/15 7-9 * * MON-FRI",
+			after:      time.Date(2025, 2, 10, 7, 20, 0, 0, time.UTC), // Monday
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 10, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Strictly after the given time",
+			expression: "*/15 7-9 * * MON-FRI",
+			after:      time.Date(2025, 2, 10, 7, 30, 0, 0, time.UTC),
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 10, 7, 45, 0, 0, time.UTC),
+		},
+		{
+			name:       "After the window moves to the next weekday",
+			expression: "*/15 7-9 * * MON-FRI",
+			after:      time.Date(2025, 2, 14, 9, 50, 0, 0, time.UTC), // Friday
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 17, 7, 0, 0, 0, time.UTC), // Monday
+		},
+		{
+			name:       "Lists and names are case insensitive",
+			expression: "0,30 8 * jan,feb tue,thu",
+			after:      time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC), // Monday
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 11, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Seven means Sunday",
+			expression: "0 9 * * 7",
+			after:      time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC),
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 16, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "Day of month or day of week when both are restricted",
+			expression: "0 9 13 * MON",
+			after:      time.Date(2025, 2, 11, 12, 0, 0, 0, time.UTC), // Tuesday
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 13, 9, 0, 0, 0, time.UTC), // Thursday the 13th
+		},
+		{
+			name:       "Step from a starting value",
+			expression: "5/20 8 * * *",
+			after:      time.Date(2025, 2, 10, 8, 6, 0, 0, time.UTC),
+			timezone:   time.UTC,
+			expected:   time.Date(2025, 2, 10, 8, 25, 0, 0, time.UTC),
+		},
+		{
+			name:       "Evaluated in the timezone",
+			expression: "30 7 * * *",
+			after:      time.Date(2025, 7, 10, 5, 0, 0, 0, time.UTC),
+			timezone:   london,
+			expected:   time.Date(2025, 7, 10, 6, 30, 0, 0, time.UTC), // 07:30 BST
+		},
+		{
+			name:       "Skips times missing due to daylight saving",
+			expression: "30 1 * * *",
+			after:      time.Date(2025, 3, 29, 12, 0, 0, 0, time.UTC), // Clocks go forward at 01:00 on the 30th
+			timezone:   london,
+			expected:   time.Date(2025, 3, 31, 0, 30, 0, 0, time.UTC), // 01:30 BST the day after
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expression)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.expression, err)
+			}
+
+			result, err := cron.Next(tt.after, tt.timezone)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !result.Equal(tt.expected) {
+				t.Errorf("Test %s failed:\nExpected: %v\nGot:      %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+func Test_CronNext_NeverMatches(t *testing.T) {
+	cron, err := ParseCron("0 9 30 FEB *")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	_, err = cron.Next(time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC), time.UTC)
+	if err == nil {
+		t.Error("Expected error for an expression which never matches, got nil")
+	}
+}
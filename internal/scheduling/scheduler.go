@@ -1,49 +1,133 @@
 package scheduling
 
 import (
+	"context"
 	"log/slog"
+	"sync"
 	"time"
 )
 
 // Schedule represents a day and time to run the function
 type Schedule struct {
-	DayOfWeek time.Weekday // e.g., time.Monday
-	Hour      int          // e.g., 14
-	Minute    int          // e.g., 30
+	DayOfWeek time.Weekday    // e.g., time.Monday
+	Hour      int             // e.g., 14
+	Minute    int             // e.g., 30
+	Cron      *CronExpression // Optional, used instead of the day and time
+}
+
+// Window represents repeated runs between two times of day on several days of the week
+type Window struct {
+	Days       []time.Weekday
+	FromHour   int
+	FromMinute int
+	ToHour     int // Inclusive
+	ToMinute   int
+	Every      time.Duration
+}
+
+// Schedules expands the window into a Schedule for each run, e.g. 07:00, 07:10, ..., 08:30
+func (w Window) Schedules() []Schedule {
+	var schedules []Schedule
+	if w.Every < time.Minute {
+		return schedules
+	}
+
+	from := time.Duration(w.FromHour)*time.Hour + time.Duration(w.FromMinute)*time.Minute
+	to := time.Duration(w.ToHour)*time.Hour + time.Duration(w.ToMinute)*time.Minute
+	for _, day := range w.Days {
+		for offset := from; offset <= to; offset += w.Every {
+			schedules = append(schedules, Schedule{
+				DayOfWeek: day,
+				Hour:      int(offset / time.Hour),
+				Minute:    int(offset % time.Hour / time.Minute),
+			})
+		}
+	}
+	return schedules
 }
 
 // Can be overridden in tests
 var getNextScheduledTimeFunction = getNextScheduledTime
 
-// ScheduleFunction holidays supplied in the format "2026-01-01"
-func ScheduleFunction(schedules []Schedule, timezone *time.Location, holidays []string, task func()) error {
+// Handle controls the runs scheduled by ScheduleFunction
+type Handle struct {
+	mu        sync.Mutex
+	timers    map[int]*time.Timer // Pending timer of each schedule
+	cancelled bool
+	running   sync.WaitGroup
+	cancel    context.CancelFunc
+}
+
+// Cancel stops all future runs. Runs already in progress continue, and see their context cancelled only if
+// the parent context is.
+func (h *Handle) Cancel() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cancelled = true
+	for _, timer := range h.timers {
+		timer.Stop()
+	}
+	h.cancel()
+}
+
+// Wait blocks until all runs in progress have finished, it should be called after Cancel
+func (h *Handle) Wait() {
+	h.running.Wait()
+}
+
+// ScheduleFunction holidays supplied in the format "2026-01-01". Scheduling stops when the context is cancelled,
+// which is also passed to the task.
+func ScheduleFunction(ctx context.Context, schedules []Schedule, timezone *time.Location, holidays []string, task func(ctx context.Context)) (*Handle, error) {
 	holidayMap := make(map[string]bool)
 	for _, h := range holidays {
 		holidayMap[h] = true
 	}
 
-	for _, schedule := range schedules {
-		schedule := schedule // Capture range variable
-		go func() {
-			var scheduleNext func(allowToday bool)
-			scheduleNext = func(allowToday bool) {
-				nextRun := getNextScheduledTimeFunction(time.Now(), schedule, timezone, allowToday, holidayMap)
-				slog.Info("Scheduled task", slog.Any("next_run", nextRun))
-
-				time.AfterFunc(time.Until(nextRun), func() {
-					go task()           // Run the task in a separate Goroutine
-					scheduleNext(false) // Recursively reschedule the next execution
-				})
+	scheduleCtx, cancel := context.WithCancel(ctx)
+	handle := &Handle{timers: make(map[int]*time.Timer), cancel: cancel}
+	context.AfterFunc(scheduleCtx, handle.Cancel)
+
+	for i, schedule := range schedules {
+		var scheduleNext func(allowToday bool)
+		scheduleNext = func(allowToday bool) {
+			handle.mu.Lock()
+			defer handle.mu.Unlock()
+			if handle.cancelled {
+				return
 			}
 
-			scheduleNext(true)
-		}()
+			nextRun := getNextScheduledTimeFunction(time.Now(), schedule, timezone, allowToday, holidayMap)
+			slog.Info("Scheduled task", slog.Any("next_run", nextRun))
+
+			handle.timers[i] = time.AfterFunc(time.Until(nextRun), func() {
+				handle.mu.Lock()
+				if handle.cancelled {
+					handle.mu.Unlock()
+					return
+				}
+				handle.running.Add(1)
+				handle.mu.Unlock()
+
+				go func() { // Run the task in a separate Goroutine
+					defer handle.running.Done()
+					task(ctx)
+				}()
+				scheduleNext(false) // Recursively reschedule the next execution
+			})
+		}
+
+		scheduleNext(true)
 	}
 
-	return nil
+	return handle, nil
 }
 
 func getNextScheduledTime(now time.Time, schedule Schedule, timezone *time.Location, allowToday bool, holidays map[string]bool) time.Time {
+	if schedule.Cron != nil {
+		return getNextCronTime(now, schedule.Cron, timezone, allowToday, holidays)
+	}
+
 	nowInTimezone := now.In(timezone)
 
 	for days := 0; days < 366; days++ {
@@ -67,3 +151,41 @@ func getNextScheduledTime(now time.Time, schedule Schedule, timezone *time.Locat
 	slog.Error("Failed to calculate next scheduled time within a year")
 	return now.Add(7 * 24 * time.Hour)
 }
+
+func getNextCronTime(now time.Time, cron *CronExpression, timezone *time.Location, allowToday bool, holidays map[string]bool) time.Time {
+	after := now
+	if !allowToday {
+		// Avoid running twice in the same minute if the previous run fired slightly early
+		after = now.Add(30 * time.Second)
+	}
+
+	for {
+		nextRun, err := cron.Next(after, timezone)
+		if err != nil {
+			slog.Error("Failed to calculate next scheduled time", slog.Any("error", err))
+			return now.Add(7 * 24 * time.Hour)
+		}
+		dateKey := nextRun.Format("2006-01-02")
+		if !holidays[dateKey] {
+			return nextRun
+		}
+		slog.Debug("Skipping scheduled run due to holiday", slog.String("date", dateKey))
+		// Continue from the last minute of the holiday
+		after = time.Date(nextRun.Year(), nextRun.Month(), nextRun.Day(), 23, 59, 0, 0, timezone)
+	}
+}
+
+// NextTimeOfDay returns the next occurrence of the hour and minute in the timezone. During that minute it returns the
+// zero time, so a journey checked at its own departure minute is requested for now rather than a time in the past,
+// which only transit requests accept.
+func NextTimeOfDay(now time.Time, hour, minute int, timezone *time.Location) time.Time {
+	nowInTimezone := now.In(timezone)
+	next := time.Date(nowInTimezone.Year(), nowInTimezone.Month(), nowInTimezone.Day(), hour, minute, 0, 0, timezone)
+	if !next.Before(now) {
+		return next
+	}
+	if next.Equal(now.Truncate(time.Minute)) {
+		return time.Time{}
+	}
+	return time.Date(nowInTimezone.Year(), nowInTimezone.Month(), nowInTimezone.Day()+1, hour, minute, 0, 0, timezone)
+}
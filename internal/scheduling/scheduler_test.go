@@ -1,6 +1,9 @@
 package scheduling
 
 import (
+	"context"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -105,6 +108,175 @@ func Test_GetNextScheduledTime(t *testing.T) {
 	}
 }
 
+func Test_GetNextScheduledTime_Cron(t *testing.T) {
+	cron, err := ParseCron("*/15 7-9 * * MON-FRI")
+	if err != nil {
+		t.Fatalf("Failed to parse cron expression: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		allowToday bool
+		holidays   map[string]bool
+		expected   time.Time
+	}{
+		{
+			name:       "Next matching time",
+			now:        time.Date(2025, 2, 10, 7, 5, 0, 0, time.UTC), // Monday
+			allowToday: true,
+			expected:   time.Date(2025, 2, 10, 7, 15, 0, 0, time.UTC),
+		},
+		{
+			name:       "Rescheduling just before the previous run does not repeat it",
+			now:        time.Date(2025, 2, 10, 7, 14, 59, 900, time.UTC),
+			allowToday: false,
+			expected:   time.Date(2025, 2, 10, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Rescheduling just after the previous run",
+			now:        time.Date(2025, 2, 10, 7, 15, 0, 1000, time.UTC),
+			allowToday: false,
+			expected:   time.Date(2025, 2, 10, 7, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "Skip holidays",
+			now:        time.Date(2025, 2, 10, 7, 5, 0, 0, time.UTC),
+			allowToday: true,
+			holidays:   map[string]bool{"2025-02-10": true, "2025-02-11": true},
+			expected:   time.Date(2025, 2, 12, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.holidays
+			if h == nil {
+				h = make(map[string]bool)
+			}
+
+			result := getNextScheduledTime(tt.now, Schedule{Cron: cron}, time.UTC, tt.allowToday, h)
+
+			if !result.Equal(tt.expected) {
+				t.Errorf("Test %s failed:\nExpected: %v\nGot:      %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+func Test_NextTimeOfDay(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		hour     int
+		minute   int
+		timezone *time.Location
+		expected time.Time
+	}{
+		{
+			name:     "Later today",
+			now:      time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC),
+			hour:     8,
+			minute:   30,
+			timezone: time.UTC,
+			expected: time.Date(2025, 2, 10, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Exactly now",
+			now:      time.Date(2025, 2, 10, 8, 30, 0, 0, time.UTC),
+			hour:     8,
+			minute:   30,
+			timezone: time.UTC,
+			expected: time.Date(2025, 2, 10, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Later in the same minute is unset rather than in the past",
+			now:      time.Date(2025, 2, 10, 8, 30, 20, 0, time.UTC),
+			hour:     8,
+			minute:   30,
+			timezone: time.UTC,
+			expected: time.Time{},
+		},
+		{
+			name:     "Already passed today",
+			now:      time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC),
+			hour:     8,
+			minute:   30,
+			timezone: time.UTC,
+			expected: time.Date(2025, 2, 11, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Evaluated in the given timezone",
+			now:      time.Date(2025, 7, 10, 6, 0, 0, 0, time.UTC), // 07:00 in London (BST)
+			hour:     8,
+			minute:   30,
+			timezone: london,
+			expected: time.Date(2025, 7, 10, 7, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NextTimeOfDay(tt.now, tt.hour, tt.minute, tt.timezone)
+
+			if !result.Equal(tt.expected) {
+				t.Errorf("Test %s failed:\nExpected: %v\nGot:      %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+func Test_WindowSchedules(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   Window
+		expected []Schedule
+	}{
+		{
+			name:   "Every 10 minutes including the end time",
+			window: Window{Days: []time.Weekday{time.Monday}, FromHour: 7, FromMinute: 40, ToHour: 8, ToMinute: 10, Every: 10 * time.Minute},
+			expected: []Schedule{
+				{DayOfWeek: time.Monday, Hour: 7, Minute: 40},
+				{DayOfWeek: time.Monday, Hour: 7, Minute: 50},
+				{DayOfWeek: time.Monday, Hour: 8, Minute: 0},
+				{DayOfWeek: time.Monday, Hour: 8, Minute: 10},
+			},
+		},
+		{
+			name:   "End time not on an interval",
+			window: Window{Days: []time.Weekday{time.Tuesday, time.Wednesday}, FromHour: 7, FromMinute: 0, ToHour: 7, ToMinute: 45, Every: 20 * time.Minute},
+			expected: []Schedule{
+				{DayOfWeek: time.Tuesday, Hour: 7, Minute: 0},
+				{DayOfWeek: time.Tuesday, Hour: 7, Minute: 20},
+				{DayOfWeek: time.Tuesday, Hour: 7, Minute: 40},
+				{DayOfWeek: time.Wednesday, Hour: 7, Minute: 0},
+				{DayOfWeek: time.Wednesday, Hour: 7, Minute: 20},
+				{DayOfWeek: time.Wednesday, Hour: 7, Minute: 40},
+			},
+		},
+		{
+			name:     "Interval shorter than a minute",
+			window:   Window{Days: []time.Weekday{time.Monday}, FromHour: 7, ToHour: 8, Every: 30 * time.Second},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.window.Schedules()
+
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Test %s failed:\nExpected: %v\nGot:      %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestScheduleFunction_TaskExecutionAndRescheduling(t *testing.T) {
 	// Save original function so we can restore it later.
 	origNextFunc := getNextScheduledTimeFunction
@@ -117,7 +289,7 @@ func TestScheduleFunction_TaskExecutionAndRescheduling(t *testing.T) {
 	// Use a channel to signal task execution.
 	executionCount := 0
 	execCh := make(chan struct{}, 10)
-	task := func() {
+	task := func(ctx context.Context) {
 		executionCount++
 		execCh <- struct{}{}
 	}
@@ -130,9 +302,11 @@ func TestScheduleFunction_TaskExecutionAndRescheduling(t *testing.T) {
 	loc := time.UTC
 
 	// Start scheduling the task.
-	if err := ScheduleFunction(schedules, loc, []string{}, task); err != nil {
+	handle, err := ScheduleFunction(context.Background(), schedules, loc, []string{}, task)
+	if err != nil {
 		t.Fatalf("ScheduleFunction returned error: %v", err)
 	}
+	defer handle.Cancel()
 
 	// Allow some time for several task executions.
 	timeout := time.After(100 * time.Millisecond)
@@ -153,3 +327,100 @@ Loop:
 		t.Errorf("expected task to execute at least twice, but got %d", executionCount)
 	}
 }
+
+func TestScheduleFunction_CancelStopsRunsAndWaitsForInFlightTask(t *testing.T) {
+	origNextFunc := getNextScheduledTimeFunction
+	getNextScheduledTimeFunction = func(now time.Time, schedule Schedule, timezone *time.Location, allowToday bool, holidays map[string]bool) time.Time {
+		return time.Now().Add(10 * time.Millisecond)
+	}
+	defer func() { getNextScheduledTimeFunction = origNextFunc }()
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	executionCount := 0
+	finished := false
+	task := func(ctx context.Context) {
+		mu.Lock()
+		executionCount++
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		finished = true
+		mu.Unlock()
+	}
+
+	handle, err := ScheduleFunction(context.Background(), []Schedule{{}}, time.UTC, nil, task)
+	if err != nil {
+		t.Fatalf("ScheduleFunction returned error: %v", err)
+	}
+
+	// Cancel while the first run is in progress
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("expected task to start")
+	}
+	handle.Cancel()
+
+	waited := make(chan struct{})
+	go func() {
+		handle.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("expected Wait to block until the in-flight task has finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("expected Wait to return once the task has finished")
+	}
+
+	// No further runs after cancellation
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if !finished {
+		t.Error("expected in-flight task to finish")
+	}
+	if executionCount != 1 {
+		t.Errorf("expected task to execute once, got %d", executionCount)
+	}
+}
+
+func TestScheduleFunction_ContextCancellationStopsRuns(t *testing.T) {
+	origNextFunc := getNextScheduledTimeFunction
+	getNextScheduledTimeFunction = func(now time.Time, schedule Schedule, timezone *time.Location, allowToday bool, holidays map[string]bool) time.Time {
+		return time.Now().Add(20 * time.Millisecond)
+	}
+	defer func() { getNextScheduledTimeFunction = origNextFunc }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	executionCount := 0
+	task := func(ctx context.Context) {
+		mu.Lock()
+		defer mu.Unlock()
+		executionCount++
+	}
+
+	handle, err := ScheduleFunction(ctx, []Schedule{{}}, time.UTC, nil, task)
+	if err != nil {
+		t.Fatalf("ScheduleFunction returned error: %v", err)
+	}
+
+	cancel()
+	time.Sleep(60 * time.Millisecond)
+	handle.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if executionCount != 0 {
+		t.Errorf("expected no runs after the context was cancelled, got %d", executionCount)
+	}
+}
@@ -0,0 +1,63 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"wayfarer/internal/notify"
+)
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Client sends messages to a Slack incoming webhook
+type Client struct {
+	WebhookUrl string
+	Logger     *slog.Logger
+}
+
+func NewClient(webhookUrl string) *Client {
+	return &Client{
+		WebhookUrl: webhookUrl,
+		Logger:     slog.Default(),
+	}
+}
+
+func (c *Client) Notify(ctx context.Context, message notify.Message) error {
+	return c.SendMessage(ctx, message.Text)
+}
+
+func (c *Client) SendMessage(ctx context.Context, message string) error {
+	payload, err := json.Marshal(Message{Text: message})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.WebhookUrl, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+
+	c.Logger.Info("Slack message sent successfully")
+	return nil
+}
@@ -0,0 +1,59 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+func TestNotify_Success(t *testing.T) {
+	// given
+	var received Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/T000/B000/XXXX" {
+			t.Errorf("unexpected URL path %q", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL + "/services/T000/B000/XXXX")
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	if received.Text != "Hello, world!" {
+		t.Errorf("expected text %q, got %q", "Hello, world!", received.Text)
+	}
+}
+
+func TestNotify_Non200Response(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("invalid_token"))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	err := client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	expectedErr := "bad status code received: 403"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}
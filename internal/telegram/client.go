@@ -2,11 +2,34 @@ package telegram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"wayfarer/internal/notify"
+)
+
+const (
+	// maxAttempts includes the first attempt at sending a message
+	maxAttempts = 3
+	// initialBackoff is the wait before retrying a server error, doubled for each further attempt
+	initialBackoff = time.Second
+	// maxRetryAfter is the longest rate limit waited for, so a message doesn't hold up an evaluation for minutes
+	maxRetryAfter = 30 * time.Second
+)
+
+// Errors for chats which can't be sent messages until the user does something, so the caller can stop trying. They
+// are all matched by notify.ErrUnreachable too.
+var (
+	ErrBotBlocked      = errors.New("bot was blocked by the user")
+	ErrChatNotFound    = errors.New("chat not found")
+	ErrUserDeactivated = errors.New("user is deactivated")
 )
 
 type Message struct {
@@ -14,10 +37,51 @@ type Message struct {
 	Text   string `json:"text"`
 }
 
+// APIError is an error response of the Bot API
+type APIError struct {
+	Code        int
+	Description string        // e.g. "Forbidden: bot was blocked by the user"
+	RetryAfter  time.Duration // How long to wait before retrying, only set when rate limited
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram API error %d: %s", e.Code, e.Description)
+}
+
+// Is matches the errors of chats which can't be sent messages. The Bot API only tells them apart by description.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBotBlocked:
+		return e.Code == http.StatusForbidden && strings.Contains(e.Description, "bot was blocked by the user")
+	case ErrChatNotFound:
+		return e.Code == http.StatusBadRequest && strings.Contains(e.Description, "chat not found")
+	case ErrUserDeactivated:
+		return e.Code == http.StatusForbidden && strings.Contains(e.Description, "user is deactivated")
+	case notify.ErrUnreachable:
+		return e.Is(ErrBotBlocked) || e.Is(ErrChatNotFound) || e.Is(ErrUserDeactivated)
+	}
+	return false
+}
+
+// errorResponse is the body of a failed Bot API request
+type errorResponse struct {
+	Ok          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"` // Seconds
+	} `json:"parameters"`
+}
+
 type Client struct {
 	ApiBaseUrl string
 	BotToken   string
 	Logger     *slog.Logger
+
+	wait func(ctx context.Context, d time.Duration) error // Optional, can be overridden in tests
+
+	mu          sync.Mutex
+	unreachable map[int64]bool // By chat ID, until ResetUnreachable
 }
 
 func NewClient(apiBaseUrl string, botToken string) *Client {
@@ -25,12 +89,19 @@ func NewClient(apiBaseUrl string, botToken string) *Client {
 		ApiBaseUrl: apiBaseUrl,
 		BotToken:   botToken,
 		Logger:     slog.Default(),
+		wait:       waitFor,
 	}
 }
 
 func (c *Client) SendMessage(chatID int64, message string) error {
-	url := fmt.Sprintf("%s/bot%s/sendMessage", c.ApiBaseUrl, c.BotToken)
+	return c.SendMessageContext(context.Background(), chatID, message)
+}
 
+// SendMessageContext sends a message, giving up when the context is cancelled. Rate limited requests are retried
+// after the delay given by Telegram, unless it is longer than maxRetryAfter or the context's deadline, and server
+// errors with exponential backoff. Other errors are returned as an
+// *APIError if Telegram described them, which matches ErrBotBlocked, ErrChatNotFound and ErrUserDeactivated.
+func (c *Client) SendMessageContext(ctx context.Context, chatID int64, message string) error {
 	msg := Message{
 		ChatID: chatID,
 		Text:   message,
@@ -45,7 +116,54 @@ func (c *Client) SendMessage(chatID int64, message string) error {
 			DisableKeepAlives: true, // We send messages infrequently, so disable keep-alives
 		},
 	}
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(payload))
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = c.send(ctx, &client, payload)
+		var apiErr *APIError
+		if err == nil || attempt == maxAttempts || !errors.As(err, &apiErr) {
+			break
+		}
+		delay := backoff
+		switch {
+		case apiErr.Code == http.StatusTooManyRequests:
+			if apiErr.RetryAfter > 0 {
+				delay = apiErr.RetryAfter
+			}
+			if deadline, ok := ctx.Deadline(); delay > maxRetryAfter || (ok && time.Now().Add(delay).After(deadline)) {
+				return err
+			}
+		case apiErr.Code >= http.StatusInternalServerError:
+			backoff *= 2
+		default:
+			return err
+		}
+		c.Logger.Warn("Failed to send message, retrying", slog.Any("error", err), slog.Int64("chat_id", chatID),
+			slog.Duration("delay", delay))
+		wait := c.wait
+		if wait == nil {
+			wait = waitFor
+		}
+		if err := wait(ctx, delay); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Logger.Info("Message sent successfully", slog.Int64("chat_id", chatID))
+	return nil
+}
+
+// send makes a single sendMessage request
+func (c *Client) send(ctx context.Context, client *http.Client, payload []byte) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", c.ApiBaseUrl, c.BotToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -56,10 +174,77 @@ func (c *Client) SendMessage(chatID int64, message string) error {
 		}
 	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Ok || body.ErrorCode == 0 {
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			// Still retried, e.g. an error page from a proxy
+			return &APIError{Code: resp.StatusCode, Description: http.StatusText(resp.StatusCode)}
+		}
 		return fmt.Errorf("bad status code received: %d", resp.StatusCode)
 	}
+	return &APIError{
+		Code:        body.ErrorCode,
+		Description: body.Description,
+		RetryAfter:  time.Duration(body.Parameters.RetryAfter) * time.Second,
+	}
+}
 
-	c.Logger.Info("Message sent successfully", slog.Int64("chat_id", chatID))
-	return nil
+// waitFor sleeps for the duration, returning early with the error of the context if it is cancelled
+func waitFor(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetUnreachable forgets which chats were unreachable, so they are tried again, e.g. when the config is reloaded
+func (c *Client) ResetUnreachable() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.unreachable)
+}
+
+func (c *Client) isUnreachable(chatID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.unreachable[chatID]
+}
+
+func (c *Client) setUnreachable(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.unreachable == nil {
+		c.unreachable = make(map[int64]bool)
+	}
+	c.unreachable[chatID] = true
+}
+
+// ChatNotifier sends notifications to a single Telegram chat. Once the chat is unreachable, e.g. the user blocked the
+// bot, no further messages are sent to it until the client's unreachable chats are reset.
+type ChatNotifier struct {
+	Client *Client
+	ChatID int64
+}
+
+func (n *ChatNotifier) Notify(ctx context.Context, message notify.Message) error {
+	if n.Client.isUnreachable(n.ChatID) {
+		return fmt.Errorf("skipped telegram chat %d: %w", n.ChatID, notify.ErrUnreachable)
+	}
+	err := n.Client.SendMessageContext(ctx, n.ChatID, message.Text)
+	if errors.Is(err, notify.ErrUnreachable) {
+		n.Client.setUnreachable(n.ChatID)
+		n.Client.Logger.Warn("Telegram chat is unreachable, not sending further messages until the config is reloaded",
+			slog.Any("error", err), slog.Int64("chat_id", n.ChatID))
+	}
+	return err
 }
@@ -1,10 +1,16 @@
 package telegram
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+	"wayfarer/internal/notify"
 )
 
 func TestSendMessage_Success(t *testing.T) {
@@ -58,3 +64,280 @@ func TestSendMessage_Non200Response(t *testing.T) {
 		t.Errorf("expected error %q, got %q", expectedErr, err.Error())
 	}
 }
+
+func TestChatNotifier_SendsToChat(t *testing.T) {
+	// given
+	var received Message
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer ts.Close()
+
+	notifier := &ChatNotifier{Client: NewClient(ts.URL, "FAKE_TOKEN"), ChatID: 12345}
+
+	// when
+	err := notifier.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := Message{ChatID: 12345, Text: "Hello, world!"}
+	if received != expected {
+		t.Errorf("expected message %+v, got %+v", expected, received)
+	}
+}
+
+func TestSendMessageContext_Cancelled(t *testing.T) {
+	// given
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client := NewClient(ts.URL, "FAKE_TOKEN")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// when
+	err := client.SendMessageContext(ctx, 12345, "Hello, world!")
+
+	// then
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded error, got %v", err)
+	}
+}
+
+// recordWaits replaces the client's waits with recording their durations
+func recordWaits(client *Client) *[]time.Duration {
+	var waits []time.Duration
+	client.wait = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return &waits
+}
+
+func TestSendMessage_RetriesAfterRateLimit(t *testing.T) {
+	// given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 3", "parameters": {"retry_after": 3}}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "FAKE_TOKEN")
+	waits := recordWaits(client)
+
+	// when
+	err := client.SendMessage(12345, "Hello, world!")
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if requests != 2 || len(*waits) != 1 || (*waits)[0] != 3*time.Second {
+		t.Errorf("expected a retry after 3s, got %d requests and waits %v", requests, *waits)
+	}
+}
+
+func TestSendMessage_BacksOffOnServerErrors(t *testing.T) {
+	// given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "FAKE_TOKEN")
+	waits := recordWaits(client)
+
+	// when
+	err := client.SendMessage(12345, "Hello, world!")
+
+	// then
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadGateway {
+		t.Fatalf("expected a bad gateway API error, got %v", err)
+	}
+	expected := []time.Duration{time.Second, 2 * time.Second}
+	if requests != maxAttempts || len(*waits) != 2 || (*waits)[0] != expected[0] || (*waits)[1] != expected[1] {
+		t.Errorf("expected %d requests with waits %v, got %d and %v", maxAttempts, expected, requests, *waits)
+	}
+}
+
+func TestSendMessage_UnreachableChat(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{
+			name:     "bot blocked",
+			status:   http.StatusForbidden,
+			body:     `{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`,
+			expected: ErrBotBlocked,
+		},
+		{
+			name:     "chat not found",
+			status:   http.StatusBadRequest,
+			body:     `{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"}`,
+			expected: ErrChatNotFound,
+		},
+		{
+			name:     "user deactivated",
+			status:   http.StatusForbidden,
+			body:     `{"ok": false, "error_code": 403, "description": "Forbidden: user is deactivated"}`,
+			expected: ErrUserDeactivated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			client := NewClient(ts.URL, "FAKE_TOKEN")
+
+			// when
+			err := client.SendMessage(12345, "Hello, world!")
+
+			// then
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+			if requests != 1 {
+				t.Errorf("expected a single request, got %d", requests)
+			}
+		})
+	}
+}
+
+func TestSendMessage_ClientWithoutConstructorRetries(t *testing.T) {
+	// given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer ts.Close()
+
+	client := &Client{ApiBaseUrl: ts.URL, BotToken: "FAKE_TOKEN", Logger: slog.Default()}
+
+	// when
+	err := client.SendMessage(12345, "Hello, world!")
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected a retry, got %d requests", requests)
+	}
+}
+
+func TestSendMessage_GivesUpOnLongRateLimit(t *testing.T) {
+	// given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"ok": false, "error_code": 429, "description": "Too Many Requests: retry after 300", "parameters": {"retry_after": 300}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "FAKE_TOKEN")
+	waits := recordWaits(client)
+
+	// when
+	err := client.SendMessage(12345, "Hello, world!")
+
+	// then
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != 300*time.Second {
+		t.Errorf("expected the rate limit error, got %v", err)
+	}
+	if requests != 1 || len(*waits) != 0 {
+		t.Errorf("expected no retry, got %d requests and waits %v", requests, *waits)
+	}
+}
+
+func TestChatNotifier_StopsSendingToBlockedChat(t *testing.T) {
+	// given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`))
+	}))
+	defer ts.Close()
+
+	notifier := &ChatNotifier{Client: NewClient(ts.URL, "FAKE_TOKEN"), ChatID: 12345}
+
+	// when
+	first := notifier.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+	second := notifier.Notify(context.Background(), notify.Message{Text: "Hello again!"})
+
+	// then
+	if !errors.Is(first, ErrBotBlocked) {
+		t.Errorf("expected the bot blocked error, got %v", first)
+	}
+	if !errors.Is(first, notify.ErrUnreachable) || !errors.Is(second, notify.ErrUnreachable) || requests != 1 {
+		t.Errorf("expected the second message to be skipped as unreachable, got %v after %d requests", second, requests)
+	}
+}
+
+func TestChatNotifier_RetriesChatAfterReset(t *testing.T) {
+	// given
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"ok": false, "error_code": 403, "description": "Forbidden: bot was blocked by the user"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "FAKE_TOKEN")
+	notifier := &ChatNotifier{Client: client, ChatID: 12345}
+	_ = notifier.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// when
+	client.ResetUnreachable()
+	err := notifier.Notify(context.Background(), notify.Message{Text: "Hello again!"})
+
+	// then
+	if err != nil || requests != 2 {
+		t.Errorf("expected the chat to be tried again, got %v after %d requests", err, requests)
+	}
+}
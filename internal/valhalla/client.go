@@ -0,0 +1,131 @@
+package valhalla
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+// Date time types understood by Valhalla
+const (
+	departAt = 1
+	arriveBy = 2
+)
+
+type location struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type dateTime struct {
+	Type  int    `json:"type"`
+	Value string `json:"value"` // Local time at the location, e.g. "2025-02-10T08:30"
+}
+
+type routeRequest struct {
+	Locations []location `json:"locations"`
+	Costing   string     `json:"costing"`
+	DateTime  *dateTime  `json:"date_time,omitempty"`
+}
+
+type routeResponse struct {
+	Trip struct {
+		Summary struct {
+			Time float64 `json:"time"` // Seconds
+		} `json:"summary"`
+	} `json:"trip"`
+	ErrorCode int    `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+// Client computes journeys using the HTTP API of a self-hosted Valhalla server
+type Client struct {
+	Url    string
+	Logger *slog.Logger
+}
+
+// NewClient takes the base URL of the Valhalla server, e.g. "http://localhost:8002"
+func NewClient(url string) *Client {
+	return &Client{
+		Url:    url,
+		Logger: slog.Default(),
+	}
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) FetchJourney(ctx context.Context, request routing.Request) (*routing.Journey, error) {
+	costing, err := toCosting(request.TravelMode)
+	if err != nil {
+		return nil, err
+	}
+
+	req := routeRequest{
+		Locations: []location{
+			{Lat: request.Origin.Latitude, Lon: request.Origin.Longitude},
+			{Lat: request.Destination.Latitude, Lon: request.Destination.Longitude},
+		},
+		Costing: costing,
+	}
+	if !request.DepartureTime.IsZero() {
+		req.DateTime = &dateTime{Type: departAt, Value: request.DepartureTime.Format("2006-01-02T15:04")}
+	}
+	if !request.ArrivalTime.IsZero() {
+		req.DateTime = &dateTime{Type: arriveBy, Value: request.ArrivalTime.Format("2006-01-02T15:04")}
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Url+"/route", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("API request to compute route failed: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	var response routeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response with status code %d: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request to compute route failed: %d %d: %s", resp.StatusCode, response.ErrorCode, response.Error)
+	}
+
+	return &routing.Journey{Duration: time.Duration(response.Trip.Summary.Time * float64(time.Second))}, nil
+}
+
+func toCosting(travelMode routing.TravelMode) (string, error) {
+	switch travelMode {
+	case routing.TravelModeDrive:
+		return "auto", nil
+	case routing.TravelModeTwoWheeler:
+		return "motorcycle", nil
+	case routing.TravelModeBicycle:
+		return "bicycle", nil
+	case routing.TravelModeWalk:
+		return "pedestrian", nil
+	case routing.TravelModeTransit:
+		return "multimodal", nil
+	default:
+		return "", fmt.Errorf("%w: %s", routing.ErrUnsupportedTravelMode, travelMode)
+	}
+}
@@ -0,0 +1,110 @@
+package valhalla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wayfarer/internal/routing"
+)
+
+func newTestRequest() routing.Request {
+	return routing.Request{
+		Origin:      routing.Location{Latitude: 51.503, Longitude: -0.1276},
+		Destination: routing.Location{Latitude: 51.498, Longitude: -0.1246},
+		TravelMode:  routing.TravelModeBicycle,
+	}
+}
+
+func TestFetchJourney_HappyPath(t *testing.T) {
+	// given
+	var received routeRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/route" {
+			t.Errorf("expected URL path %q, got %q", "/route", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"trip":{"summary":{"time":754.2,"length":3.1},"status":0}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	journey, err := client.FetchJourney(context.Background(), newTestRequest())
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := time.Duration(754.2 * float64(time.Second))
+	if journey.Duration != expected {
+		t.Errorf("expected duration %v, got %v", expected, journey.Duration)
+	}
+	if received.Costing != "bicycle" {
+		t.Errorf("expected bicycle costing, got %q", received.Costing)
+	}
+	if len(received.Locations) != 2 || received.Locations[0] != (location{Lat: 51.503, Lon: -0.1276}) || received.Locations[1] != (location{Lat: 51.498, Lon: -0.1246}) {
+		t.Errorf("unexpected locations: %+v", received.Locations)
+	}
+	if received.DateTime != nil {
+		t.Errorf("expected no date time, got %+v", received.DateTime)
+	}
+}
+
+func TestFetchJourney_DepartureTime(t *testing.T) {
+	// given
+	var received routeRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"trip":{"summary":{"time":754.2}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	request := newTestRequest()
+	request.TravelMode = routing.TravelModeDrive
+	request.DepartureTime = time.Date(2025, 2, 10, 8, 30, 0, 0, time.UTC)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), request)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := dateTime{Type: departAt, Value: "2025-02-10T08:30"}
+	if received.DateTime == nil || *received.DateTime != expected {
+		t.Errorf("expected date time %+v, got %+v", expected, received.DateTime)
+	}
+	if received.Costing != "auto" {
+		t.Errorf("expected auto costing, got %q", received.Costing)
+	}
+}
+
+func TestFetchJourney_ErrorResponse(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error_code":442,"error":"No path could be found for input","status_code":400}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+
+	// when
+	_, err := client.FetchJourney(context.Background(), newTestRequest())
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "No path could be found for input") {
+		t.Errorf("expected error to contain 'No path could be found for input', got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"text/template"
+	"wayfarer/internal/notify"
+)
+
+// DefaultBodyTemplate is used when no body template is configured
+const DefaultBodyTemplate = `{"text": {{json .Text}}}`
+
+// Client posts messages to an arbitrary webhook, rendering the request body from a template
+type Client struct {
+	Url          string
+	BodyTemplate *template.Template
+	Logger       *slog.Logger
+}
+
+// NewClient parses the body template, which is given the notify.Message, e.g. `{"message": {{json .Text}}}`
+func NewClient(url string, bodyTemplate string) (*Client, error) {
+	if bodyTemplate == "" {
+		bodyTemplate = DefaultBodyTemplate
+	}
+	tmpl, err := template.New("body").Funcs(template.FuncMap{"json": toJson}).Parse(bodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhook body template: %w", err)
+	}
+
+	return &Client{
+		Url:          url,
+		BodyTemplate: tmpl,
+		Logger:       slog.Default(),
+	}, nil
+}
+
+func (c *Client) Notify(ctx context.Context, message notify.Message) error {
+	var body bytes.Buffer
+	if err := c.BodyTemplate.Execute(&body, message); err != nil {
+		return fmt.Errorf("failed to render webhook body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.Logger.Error("Failed to close response body", slog.Any("error", err))
+		}
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("bad status code received: %d", resp.StatusCode)
+	}
+
+	c.Logger.Info("Webhook called successfully")
+	return nil
+}
+
+// toJson quotes and escapes values so they can be safely embedded in a JSON body
+func toJson(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
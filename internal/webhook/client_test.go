@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wayfarer/internal/notify"
+)
+
+func TestNotify_DefaultTemplate(t *testing.T) {
+	// given
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// when
+	err = client.Notify(context.Background(), notify.Message{Text: `Travel time is "long"`})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := `{"text": "Travel time is \"long\""}`
+	if received != expected {
+		t.Errorf("expected body %q, got %q", expected, received)
+	}
+}
+
+func TestNotify_CustomTemplate(t *testing.T) {
+	// given
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, `{"source": "wayfarer", "message": {{json .Text}}}`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// when
+	err = client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// then
+	expected := `{"source": "wayfarer", "message": "Hello, world!"}`
+	if received != expected {
+		t.Errorf("expected body %q, got %q", expected, received)
+	}
+}
+
+func TestNewClient_InvalidTemplate(t *testing.T) {
+	_, err := NewClient("http://localhost", `{"text": {{json .Text}`)
+	if err == nil {
+		t.Fatal("expected an error due to invalid template, got nil")
+	}
+}
+
+func TestNotify_Non2xxResponse(t *testing.T) {
+	// given
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	// when
+	err = client.Notify(context.Background(), notify.Message{Text: "Hello, world!"})
+
+	// then
+	expectedErr := "bad status code received: 500"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("expected error %q, got %v", expectedErr, err)
+	}
+}